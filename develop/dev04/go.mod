@@ -1,3 +1,5 @@
 module wb_l2_dev04
 
 go 1.22.5
+
+require golang.org/x/text v0.16.0
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Формат файла индекса:
+//
+//	magic "ANIX", версия (1 байт), настройки нормализации (1 байт, с версии 2),
+//	число групп (uvarint), далее для каждой группы:
+//	ключ (uvarint длина + байты), число слов (uvarint), слова (uvarint длина + байты).
+//
+// Ключ хранится в файле, чтобы при загрузке не пересортировывать руны каждого слова.
+const (
+	indexMagic   = "ANIX"
+	indexVersion = 2
+
+	maxIndexString = 1 << 20 // ограничение длины строки, защищает от мусора в файле
+)
+
+// ErrBadIndex возвращается при чтении повреждённого или чужого файла индекса
+var ErrBadIndex = errors.New("bad anagram index file")
+
+// Index - индекс анаграмм: ключ (отсортированные руны слова) -> отсортированный список слов.
+// Безопасен для одновременного использования из нескольких горутин.
+type Index struct {
+	mu     sync.RWMutex
+	opts   Options
+	groups map[string][]string
+	size   int // общее число слов в индексе
+}
+
+// NewIndex создает пустой индекс
+func NewIndex() *Index {
+	return NewIndexWithOptions(Options{})
+}
+
+// NewIndexWithOptions создает пустой индекс с заданной нормализацией слов
+func NewIndexWithOptions(opts Options) *Index {
+	return &Index{opts: opts, groups: make(map[string][]string)}
+}
+
+// BuildIndex строит индекс по словарю
+func BuildIndex(words []string) *Index {
+	return BuildIndexWithOptions(words, Options{})
+}
+
+// BuildIndexWithOptions строит индекс по словарю с заданной нормализацией слов
+func BuildIndexWithOptions(words []string, opts Options) *Index {
+	idx := NewIndexWithOptions(opts)
+	for _, word := range words {
+		idx.add(word)
+	}
+	return idx
+}
+
+// Options возвращает настройки нормализации индекса
+func (idx *Index) Options() Options {
+	return idx.opts
+}
+
+// Add добавляет слово в индекс. Возвращает false, если слово уже было в индексе
+func (idx *Index) Add(word string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.add(word)
+}
+
+// Remove удаляет слово из индекса. Возвращает false, если слова в индексе не было
+func (idx *Index) Remove(word string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	key, word, ok := idx.lookup(word)
+	if !ok {
+		return false
+	}
+	group := idx.groups[key]
+
+	i := sort.SearchStrings(group, word)
+	if i == len(group) || group[i] != word {
+		return false
+	}
+
+	if len(group) == 1 {
+		delete(idx.groups, key)
+	} else {
+		idx.groups[key] = append(group[:i], group[i+1:]...)
+	}
+	idx.size--
+	return true
+}
+
+// Anagrams возвращает все слова индекса, составленные из тех же букв, что и word,
+// кроме самого word. Результат отсортирован по возрастанию и принадлежит вызывающему
+func (idx *Index) Anagrams(word string) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	key, word, _ := idx.lookup(word)
+	group := idx.groups[key]
+
+	result := make([]string, 0, len(group))
+	for _, w := range group {
+		if w != word {
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+// Contains сообщает, есть ли слово в индексе
+func (idx *Index) Contains(word string) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	key, word, _ := idx.lookup(word)
+	group := idx.groups[key]
+	i := sort.SearchStrings(group, word)
+	return i < len(group) && group[i] == word
+}
+
+// Len возвращает число слов в индексе
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.size
+}
+
+// Функция возвращает ключ слова и его вид в индексе. ok=false, если после нормализации слово пустое
+func (idx *Index) lookup(word string) (key, stored string, ok bool) {
+	normalized := idx.opts.normalize(word)
+	return idx.opts.key(normalized), idx.opts.output(word, normalized), normalized != ""
+}
+
+// Функция добавляет слово в группу, сохраняя порядок сортировки. Вызывается под блокировкой
+func (idx *Index) add(word string) bool {
+	key, word, ok := idx.lookup(word)
+	if !ok {
+		return false
+	}
+	group := idx.groups[key]
+
+	i := sort.SearchStrings(group, word)
+	if i < len(group) && group[i] == word {
+		return false
+	}
+
+	group = append(group, "")
+	copy(group[i+1:], group[i:])
+	group[i] = word
+	idx.groups[key] = group
+	idx.size++
+	return true
+}
+
+// WriteTo сериализует индекс в компактный бинарный формат
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	// ключи пишем в отсортированном порядке, чтобы файл не зависел от порядка обхода мапы
+	keys := make([]string, 0, len(idx.groups))
+	for key := range idx.groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	cw.writeString(indexMagic)
+	cw.writeByte(indexVersion)
+	cw.writeByte(idx.opts.encode())
+	cw.writeUvarint(uint64(len(keys)))
+	for _, key := range keys {
+		group := idx.groups[key]
+		cw.writeBytes(key)
+		cw.writeUvarint(uint64(len(group)))
+		for _, word := range group {
+			cw.writeBytes(word)
+		}
+	}
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// ReadIndex читает индекс, записанный WriteTo
+func ReadIndex(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(indexMagic)+1)
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadIndex, err)
+	}
+	if string(magic[:len(indexMagic)]) != indexMagic {
+		return nil, fmt.Errorf("%w: wrong magic", ErrBadIndex)
+	}
+
+	// файлы версии 1 записаны без настроек и всегда используют нормализацию по умолчанию
+	var opts Options
+	switch version := magic[len(indexMagic)]; version {
+	case 1:
+	case indexVersion:
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadIndex, err)
+		}
+		var ok bool
+		if opts, ok = decodeOptions(b); !ok {
+			return nil, fmt.Errorf("%w: unknown options %#x", ErrBadIndex, b)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrBadIndex, version)
+	}
+
+	groupCount, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadIndex, err)
+	}
+
+	idx := NewIndexWithOptions(opts)
+	for ; groupCount > 0; groupCount-- {
+		key, err := readBytes(br)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := idx.groups[key]; ok {
+			return nil, fmt.Errorf("%w: duplicate group %q", ErrBadIndex, key)
+		}
+		wordCount, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadIndex, err)
+		}
+		if wordCount == 0 {
+			return nil, fmt.Errorf("%w: empty group %q", ErrBadIndex, key)
+		}
+
+		group := make([]string, 0, min(wordCount, 1024))
+		for ; wordCount > 0; wordCount-- {
+			word, err := readBytes(br)
+			if err != nil {
+				return nil, err
+			}
+			if n := len(group); n > 0 && group[n-1] >= word {
+				return nil, fmt.Errorf("%w: group %q is not sorted", ErrBadIndex, key)
+			}
+			// ключ слова пересчитываем: файл могли повредить или записать с другими настройками,
+			// а слово с чужим ключом не нашли бы Anagrams, Contains и Remove
+			if wordKey, _, ok := idx.lookup(word); !ok || wordKey != key {
+				return nil, fmt.Errorf("%w: word %q does not belong to group %q", ErrBadIndex, word, key)
+			}
+			group = append(group, word)
+		}
+		idx.groups[key] = group
+		idx.size += len(group)
+	}
+	return idx, nil
+}
+
+// Save сохраняет индекс в файл
+func (idx *Index) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating index file: %v", err)
+	}
+	if _, err := idx.WriteTo(file); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing index file: %v", err)
+	}
+	return file.Close()
+}
+
+// LoadIndex загружает индекс из файла
+func LoadIndex(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening index file: %v", err)
+	}
+	defer file.Close()
+	return ReadIndex(file)
+}
+
+// Функция читает строку в формате uvarint длина + байты
+func readBytes(br *bufio.Reader) (string, error) {
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrBadIndex, err)
+	}
+	if n > maxIndexString {
+		return "", fmt.Errorf("%w: string length %d is too large", ErrBadIndex, n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(br, buf); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrBadIndex, err)
+	}
+	return string(buf), nil
+}
+
+// countingWriter считает записанные байты и запоминает первую ошибку
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (cw *countingWriter) writeString(s string) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.WriteString(s)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+func (cw *countingWriter) writeByte(b byte) {
+	if cw.err != nil {
+		return
+	}
+	cw.err = cw.w.WriteByte(b)
+	if cw.err == nil {
+		cw.n++
+	}
+}
+
+func (cw *countingWriter) writeUvarint(v uint64) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.Write(cw.buf[:binary.PutUvarint(cw.buf[:], v)])
+	cw.n += int64(n)
+	cw.err = err
+}
+
+func (cw *countingWriter) writeBytes(s string) {
+	cw.writeUvarint(uint64(len(s)))
+	cw.writeString(s)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormForm - каноническая форма Unicode, к которой приводятся слова
+type NormForm int
+
+const (
+	// NormNone - слова не нормализуются
+	NormNone NormForm = iota
+	// NormNFC - каноническая композиция ("е" + U+0308 -> "ё")
+	NormNFC
+	// NormNFD - каноническая декомпозиция ("ё" -> "е" + U+0308)
+	NormNFD
+)
+
+// KeyPolicy - выбор слова, которое становится ключом множества анаграмм в результате
+type KeyPolicy int
+
+const (
+	// KeyFirstSeen - первое встретившееся в словаре слово множества
+	KeyFirstSeen KeyPolicy = iota
+	// KeyAlphabetical - первое по алфавиту слово множества
+	KeyAlphabetical
+)
+
+// Options - настройки построения ключей анаграмм и вывода слов.
+// Нулевое значение соответствует заданию: регистр понижается, руны сравниваются как есть,
+// ключ - первое встретившееся слово. Key не влияет на Index и не сохраняется в файл индекса
+type Options struct {
+	Form         NormForm  // каноническая форма Unicode
+	FoldYo       bool      // считать «ё» и «е» одной буквой
+	LettersOnly  bool      // отбрасывать все символы, кроме букв (дефисы, апострофы, пробелы, цифры)
+	KeepOriginal bool      // выводить слово в исходном написании (в нижнем регистре и канонической форме)
+	Key          KeyPolicy // выбор ключа множества в результате FindAnagrams
+}
+
+// Функция приводит слово к виду, по которому строится ключ
+func (o Options) normalize(word string) string {
+	word = strings.ToLower(word)
+
+	// «ё» складываем в составной форме, поэтому сначала собираем декомпозированные буквы
+	if o.Form != NormNone || o.FoldYo {
+		word = norm.NFC.String(word)
+	}
+	if o.FoldYo {
+		word = strings.ReplaceAll(word, "ё", "е")
+	}
+	if o.LettersOnly {
+		// диакритические знаки оставляем, чтобы в NFD «й» не превращалась в «и»
+		word = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsMark(r) {
+				return r
+			}
+			return -1
+		}, word)
+	}
+	if o.Form == NormNFD {
+		word = norm.NFD.String(word)
+	}
+	return word
+}
+
+// Функция возвращает слово в том виде, в котором оно попадает в результат
+func (o Options) output(word, normalized string) string {
+	if !o.KeepOriginal {
+		return normalized
+	}
+
+	word = strings.ToLower(word)
+	switch o.Form {
+	case NormNFC:
+		word = norm.NFC.String(word)
+	case NormNFD:
+		word = norm.NFD.String(word)
+	}
+	return word
+}
+
+// Функция возвращает ключ анаграммы для уже нормализованного слова
+func (o Options) key(normalized string) string {
+	return sortString(normalized)
+}
+
+// Функция кодирует настройки в байт для файла индекса
+func (o Options) encode() byte {
+	b := byte(o.Form) & 0x03
+	if o.FoldYo {
+		b |= 1 << 2
+	}
+	if o.LettersOnly {
+		b |= 1 << 3
+	}
+	if o.KeepOriginal {
+		b |= 1 << 4
+	}
+	return b
+}
+
+// Функция восстанавливает настройки из байта файла индекса
+func decodeOptions(b byte) (Options, bool) {
+	if b>>5 != 0 || NormForm(b&0x03) > NormNFD {
+		return Options{}, false
+	}
+	return Options{
+		Form:         NormForm(b & 0x03),
+		FoldYo:       b&(1<<2) != 0,
+		LettersOnly:  b&(1<<3) != 0,
+		KeepOriginal: b&(1<<4) != 0,
+	}, true
+}
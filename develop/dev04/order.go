@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Group - множество анаграмм вместе с ключом
+type Group struct {
+	Key   string
+	Words []string
+}
+
+// SortOrder - порядок групп в упорядоченном результате
+type SortOrder int
+
+const (
+	// ByKey - по возрастанию ключа
+	ByKey SortOrder = iota
+	// BySize - по убыванию числа слов, при равенстве по возрастанию ключа
+	BySize
+)
+
+// OrderedGroups возвращает множества из результата FindAnagrams в детерминированном порядке
+func OrderedGroups(groups map[string][]string, order SortOrder) []Group {
+	result := make([]Group, 0, len(groups))
+	for key, words := range groups {
+		result = append(result, Group{Key: key, Words: words})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if order == BySize && len(result[i].Words) != len(result[j].Words) {
+			return len(result[i].Words) > len(result[j].Words)
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// PrintGroups печатает множества по одному на строку в формате "ключ: слово, слово"
+func PrintGroups(w io.Writer, groups []Group) error {
+	bw := bufio.NewWriter(w)
+	for _, group := range groups {
+		bw.WriteString(group.Key)
+		bw.WriteString(": ")
+		bw.WriteString(strings.Join(group.Words, ", "))
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"hash/fnv"
+	"runtime"
+	"sync"
+)
+
+// keyedWord - слово словаря вместе с ключом анаграммы
+type keyedWord struct {
+	key  string
+	word string
+}
+
+// FindAnagramsParallel ищет все множества анаграмм по словарю на нескольких горутинах.
+// Результат совпадает с FindAnagrams. workers <= 0 - по числу доступных процессоров
+func FindAnagramsParallel(words *[]string, workers int) *map[string][]string {
+	return FindAnagramsParallelWithOptions(words, Options{}, workers)
+}
+
+// FindAnagramsParallelWithOptions - параллельный вариант FindAnagramsWithOptions.
+//
+// Словарь делится на непрерывные куски, ключи для каждого куска считаются на своей горутине
+// и раскладываются по шардам по хешу ключа. Затем каждый шард группирует свои слова,
+// обходя куски по порядку, поэтому слова попадают в группы в том же порядке, что и при
+// последовательном проходе. Одинаковые слова имеют одинаковый ключ и всегда попадают
+// в один шард, так что дубликаты отсеиваются внутри шарда
+func FindAnagramsParallelWithOptions(words *[]string, opts Options, workers int) *map[string][]string {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	list := *words
+	if workers > len(list) {
+		workers = len(list)
+	}
+
+	result := make(map[string][]string)
+	if workers == 0 {
+		return &result
+	}
+	shards := workers
+
+	// buckets[w][s] - слова куска w, попавшие в шард s, в исходном порядке
+	buckets := make([][][]keyedWord, workers)
+	chunkSize := (len(list) + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start := w * chunkSize
+		if start >= len(list) {
+			break
+		}
+		end := min(start+chunkSize, len(list))
+
+		wg.Add(1)
+		go func(w int, chunk []string) {
+			defer wg.Done()
+
+			local := make([][]keyedWord, shards)
+			for _, word := range chunk {
+				normalized := opts.normalize(word)
+				if normalized == "" {
+					continue
+				}
+				key := opts.key(normalized)
+				s := shardOf(key, shards)
+				local[s] = append(local[s], keyedWord{key: key, word: opts.output(word, normalized)})
+			}
+			buckets[w] = local
+		}(w, list[start:end])
+	}
+	wg.Wait()
+
+	// группируем слова каждого шарда независимо
+	shardResults := make([]map[string][]string, shards)
+	for s := 0; s < shards; s++ {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+
+			anagramGroups := make(map[string][]string)
+			seenWords := make(map[string]bool)
+			for _, local := range buckets {
+				if local == nil {
+					continue
+				}
+				for _, kw := range local[s] {
+					if seenWords[kw.word] {
+						continue
+					}
+					anagramGroups[kw.key] = append(anagramGroups[kw.key], kw.word)
+					seenWords[kw.word] = true
+				}
+			}
+
+			shardResults[s] = make(map[string][]string)
+			collectGroups(anagramGroups, shardResults[s], opts.Key)
+		}(s)
+	}
+	wg.Wait()
+
+	// ключи шардов не пересекаются, поэтому слияние не зависит от порядка
+	for _, part := range shardResults {
+		for key, group := range part {
+			result[key] = group
+		}
+	}
+	return &result
+}
+
+// Функция возвращает номер шарда для ключа анаграммы
+func shardOf(key string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(shards))
+}
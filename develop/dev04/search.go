@@ -0,0 +1,197 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PhraseOptions - ограничения поиска фразовых анаграмм. Нулевое значение поля - без ограничения
+type PhraseOptions struct {
+	MaxResults int // максимальное число найденных фраз
+	MaxWords   int // максимальное число слов во фразе
+	MinWordLen int // минимальная длина слова фразы в буквах
+}
+
+// SubAnagrams возвращает слова индекса, которые можно составить из части букв letters
+// (каждая буква используется не больше раз, чем встречается в letters).
+// Слова упорядочены по убыванию длины, затем по возрастанию. limit <= 0 - без ограничения
+func (idx *Index) SubAnagrams(letters string, limit int) []string {
+	have := idx.lettersKey(letters)
+
+	idx.mu.RLock()
+	var result []string
+	for key, group := range idx.groups {
+		if containsRunes(have, []rune(key)) {
+			result = append(result, group...)
+		}
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		li, lj := utf8.RuneCountInString(result[i]), utf8.RuneCountInString(result[j])
+		if li != lj {
+			return li > lj
+		}
+		return result[i] < result[j]
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// PhraseAnagrams разбивает все буквы фразы на слова индекса.
+// Пробелы во фразе игнорируются, каждая фраза-результат использует все буквы ровно один раз.
+// Слова во фразе идут по убыванию длины, одна и та же комбинация слов возвращается один раз
+func (idx *Index) PhraseAnagrams(phrase string, opts PhraseOptions) [][]string {
+	have := idx.lettersKey(phrase)
+	if len(have) == 0 {
+		return nil
+	}
+
+	// кандидаты - группы, буквы которых целиком содержатся во фразе.
+	// Слова копируются, чтобы долгий перебор шел без блокировки и не мешал Add и Remove
+	idx.mu.RLock()
+	var candidates []phraseCandidate
+	for key, group := range idx.groups {
+		runes := []rune(key)
+		if len(runes) >= opts.MinWordLen && containsRunes(have, runes) {
+			candidates = append(candidates, phraseCandidate{runes: runes, words: append([]string(nil), group...)})
+		}
+	}
+	idx.mu.RUnlock()
+
+	// сначала длинные слова: так результаты естественнее, а перебор быстрее отсекается
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i].runes, candidates[j].runes
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return string(a) < string(b)
+	})
+
+	s := &phraseSearch{candidates: candidates, opts: opts}
+	s.walk(0, have, nil)
+	return s.results
+}
+
+// phraseCandidate - группа анаграмм, которая может войти во фразу
+type phraseCandidate struct {
+	runes []rune   // отсортированные буквы группы
+	words []string // слова группы
+}
+
+// phraseSearch - состояние перебора фразовых анаграмм
+type phraseSearch struct {
+	candidates []phraseCandidate
+	opts       PhraseOptions
+	results    [][]string
+}
+
+// Функция перебирает группы, начиная с start, пока не израсходованы все буквы remaining.
+// Группы выбираются в неубывающем порядке индексов, поэтому каждое сочетание встречается один раз
+func (s *phraseSearch) walk(start int, remaining []rune, chosen []int) {
+	if s.done() {
+		return
+	}
+	if len(remaining) == 0 {
+		s.expand(chosen, 0, 0, nil)
+		return
+	}
+
+	wordsLeft := s.opts.MaxWords - len(chosen)
+	if s.opts.MaxWords > 0 && wordsLeft == 0 {
+		return
+	}
+
+	for i := start; i < len(s.candidates) && !s.done(); i++ {
+		runes := s.candidates[i].runes
+		// кандидаты отсортированы по убыванию длины: если даже самыми длинными
+		// из оставшихся словами буквы не покрыть, дальше искать бесполезно
+		if s.opts.MaxWords > 0 && len(runes)*wordsLeft < len(remaining) {
+			return
+		}
+		if rest, ok := subtractRunes(remaining, runes); ok {
+			s.walk(i, rest, append(chosen, i))
+		}
+	}
+}
+
+// Функция раскрывает выбранные группы в конкретные фразы.
+// Для повторяющейся группы слова выбираются в неубывающем порядке, чтобы не плодить перестановки
+func (s *phraseSearch) expand(chosen []int, pos, minWord int, phrase []string) {
+	if s.done() {
+		return
+	}
+	if pos == len(chosen) {
+		s.results = append(s.results, append([]string(nil), phrase...))
+		return
+	}
+
+	if pos == 0 || chosen[pos] != chosen[pos-1] {
+		minWord = 0
+	}
+	words := s.candidates[chosen[pos]].words
+	for w := minWord; w < len(words); w++ {
+		s.expand(chosen, pos+1, w, append(phrase, words[w]))
+	}
+}
+
+// Функция сообщает, набрано ли нужное число результатов
+func (s *phraseSearch) done() bool {
+	return s.opts.MaxResults > 0 && len(s.results) >= s.opts.MaxResults
+}
+
+// Функция возвращает отсортированные буквы строки без пробельных символов,
+// нормализованные так же, как слова индекса
+func (idx *Index) lettersKey(s string) []rune {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return []rune(idx.opts.key(idx.opts.normalize(s)))
+}
+
+// Функция проверяет, что отсортированное мультимножество need содержится в отсортированном have
+func containsRunes(have, need []rune) bool {
+	if len(need) > len(have) {
+		return false
+	}
+	i := 0
+	for _, r := range need {
+		for i < len(have) && have[i] < r {
+			i++
+		}
+		if i == len(have) || have[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// Функция вычитает отсортированное мультимножество need из have.
+// Возвращает false, если need не содержится в have
+func subtractRunes(have, need []rune) ([]rune, bool) {
+	if len(need) > len(have) {
+		return nil, false
+	}
+	rest := make([]rune, 0, len(have)-len(need))
+	i := 0
+	for _, r := range need {
+		for i < len(have) && have[i] < r {
+			rest = append(rest, have[i])
+			i++
+		}
+		if i == len(have) || have[i] != r {
+			return nil, false
+		}
+		i++
+	}
+	return append(rest, have[i:]...), true
+}
@@ -1,8 +1,9 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"sort"
-	"strings"
 )
 
 /*
@@ -26,38 +27,58 @@ import (
 
 // FindAnagrams ищет все множества анаграмм по словарю
 func FindAnagrams(words *[]string) *map[string][]string {
+	return FindAnagramsWithOptions(words, Options{})
+}
+
+// FindAnagramsWithOptions ищет все множества анаграмм по словарю с заданной нормализацией слов
+func FindAnagramsWithOptions(words *[]string, opts Options) *map[string][]string {
 	anagramGroups := make(map[string][]string)	// временная мапа для групп анаграмм, где ключ - сортированное слово
 	seenWords := make(map[string]bool)	// мапа слов, кот. уже были добавлены
 
 	for _, word := range *words {
-		// приводим к нижнемсу регистру
-		lowerWord := strings.ToLower(word)
+		// приводим к нижнему регистру и нормализуем
+		normalized := opts.normalize(word)
+		if normalized == "" {
+			continue
+		}
+		outWord := opts.output(word, normalized)
 
 		// пропуск добавленного слова
-		if seenWords[lowerWord] {
+		if seenWords[outWord] {
 			continue
 		}
 
 		// создаем ключ
-		key := sortString(lowerWord)
+		key := opts.key(normalized)
 
 		// добавление слова в группу
-		anagramGroups[key] = append(anagramGroups[key], lowerWord)
+		anagramGroups[key] = append(anagramGroups[key], outWord)
 
 		// помечаем слово как добавленное
-		seenWords[lowerWord] = true
+		seenWords[outWord] = true
 	}
 
 	// итоговая мапа без групп из одного эл-та
 	result := make(map[string][]string)
+	collectGroups(anagramGroups, result, opts.Key)
+
+	return &result
+}
+
+// Функция переносит в result группы из более чем одного слова, отсортировав их.
+// Слова в группах должны идти в порядке появления в словаре
+func collectGroups(anagramGroups map[string][]string, result map[string][]string, policy KeyPolicy) {
 	for _, group := range anagramGroups {
 		if len(group) > 1 {
+			firstSeen := group[0]
 			sort.Strings(group) // сортируем группу
-			result[group[0]] = group
+			if policy == KeyFirstSeen {
+				result[firstSeen] = group
+			} else {
+				result[group[0]] = group
+			}
 		}
 	}
-
-	return &result
 }
 
 // Функция возвращает отсортированную строку для ключа анаграмм
@@ -73,8 +94,9 @@ func main() {
 	words := []string{"пятак", "пятка", "тяпка", "листок", "слиток", "столик", "аптек", "пятак", "Пятка"}
 	result := FindAnagrams(&words)
 
-	// Вывод результата
-	for key, group := range *result {
-		println(key + ": " + strings.Join(group, ", "))
+	// Вывод результата в стабильном порядке
+	if err := PrintGroups(os.Stdout, OrderedGroups(*result, ByKey)); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing output:", err)
+		os.Exit(1)
 	}
 }
@@ -1,7 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
 	"reflect"
+	"runtime"
 	"testing"
 )
 
@@ -38,7 +45,7 @@ func TestFindAnagrams(t *testing.T) {
 			words: []string{"пятак", "пятка", "тяпка", "дом", "слово", "ловос"},
 			expected: map[string][]string{
 				"пятак": {"пятак", "пятка", "тяпка"},
-				"ловос": {"ловос", "слово"},
+				"слово": {"ловос", "слово"},
 			},
 		},
 	}
@@ -54,3 +61,561 @@ func TestFindAnagrams(t *testing.T) {
 		})
 	}
 }
+
+func TestIndexAnagrams(t *testing.T) {
+	idx := BuildIndex([]string{"пятак", "Пятка", "тяпка", "листок", "слиток", "дом", "пятак"})
+
+	if idx.Len() != 6 {
+		t.Errorf("Expected 6 words in index, got %d", idx.Len())
+	}
+
+	tests := []struct {
+		word     string
+		expected []string
+	}{
+		{"пятак", []string{"пятка", "тяпка"}},
+		{"ТЯПКА", []string{"пятак", "пятка"}},
+		{"катяп", []string{"пятак", "пятка", "тяпка"}}, // слова нет в словаре, но анаграммы есть
+		{"слиток", []string{"листок"}},
+		{"дом", []string{}},
+		{"кот", []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.word, func(t *testing.T) {
+			result := idx.Anagrams(test.word)
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("Expected %v, but got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestIndexAddRemove(t *testing.T) {
+	idx := NewIndex()
+
+	if !idx.Add("листок") || !idx.Add("Столик") {
+		t.Fatal("Expected new words to be added")
+	}
+	if idx.Add("столик") {
+		t.Error("Expected duplicate word not to be added")
+	}
+	if !idx.Add("слиток") {
+		t.Fatal("Expected new word to be added")
+	}
+
+	expected := []string{"слиток", "столик"}
+	if result := idx.Anagrams("листок"); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, but got %v", expected, result)
+	}
+
+	if !idx.Remove("слиток") {
+		t.Error("Expected existing word to be removed")
+	}
+	if idx.Remove("слиток") {
+		t.Error("Expected missing word not to be removed")
+	}
+	if idx.Contains("слиток") || !idx.Contains("СТОЛИК") {
+		t.Error("Contains does not reflect index content")
+	}
+
+	idx.Remove("листок")
+	idx.Remove("столик")
+	if idx.Len() != 0 || len(idx.groups) != 0 {
+		t.Errorf("Expected empty index, got %d words in %d groups", idx.Len(), len(idx.groups))
+	}
+}
+
+func TestIndexSaveLoad(t *testing.T) {
+	idx := BuildIndex([]string{"пятак", "пятка", "тяпка", "листок", "слиток", "столик", "дом"})
+	path := filepath.Join(t.TempDir(), "words.idx")
+
+	if err := idx.Save(path); err != nil {
+		t.Fatalf("Error saving index: %v", err)
+	}
+	loaded, err := LoadIndex(path)
+	if err != nil {
+		t.Fatalf("Error loading index: %v", err)
+	}
+
+	if !reflect.DeepEqual(loaded.groups, idx.groups) || loaded.Len() != idx.Len() {
+		t.Errorf("Expected %v, but got %v", idx.groups, loaded.groups)
+	}
+
+	// одинаковый индекс всегда сериализуется одинаково
+	var first, second bytes.Buffer
+	idx.WriteTo(&first)
+	loaded.WriteTo(&second)
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("Expected identical serialized indexes")
+	}
+}
+
+func TestReadIndexBadData(t *testing.T) {
+	var buf bytes.Buffer
+	BuildIndex([]string{"пятак", "пятка"}).WriteTo(&buf)
+	data := buf.Bytes()
+
+	// заголовок: магия, версия и настройки, затем число групп (1) и сама группа
+	const header = len(indexMagic) + 2
+	group := data[header+1:]
+	duplicate := append(append(append(append([]byte{}, data[:header]...), 2), group...), group...)
+
+	// группы собираем вручную: ключ, затем слова группы
+	encode := func(groups ...[]string) []byte {
+		b := append([]byte{}, data[:header]...)
+		b = binary.AppendUvarint(b, uint64(len(groups)))
+		for _, g := range groups {
+			b = binary.AppendUvarint(b, uint64(len(g[0])))
+			b = append(b, g[0]...)
+			b = binary.AppendUvarint(b, uint64(len(g)-1))
+			for _, word := range g[1:] {
+				b = binary.AppendUvarint(b, uint64(len(word)))
+				b = append(b, word...)
+			}
+		}
+		return b
+	}
+	if _, err := ReadIndex(bytes.NewReader(encode([]string{"акптя", "пятак"}, []string{"кот", "кот"}))); err != nil {
+		t.Fatalf("Expected a valid hand-built index, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", nil},
+		{"Wrong magic", append([]byte("XXXX"), data[4:]...)},
+		{"Wrong version", append([]byte("ANIX\x09"), data[5:]...)},
+		{"Truncated", data[:len(data)-3]},
+		{"Duplicate group", duplicate},
+		{"Wrong key", encode([]string{"акптя", "кот", "пятак"})},
+		{"Word in two groups", encode([]string{"акптя", "пятак"}, []string{"кот", "кот", "пятак"})},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadIndex(bytes.NewReader(test.data))
+			if !errors.Is(err, ErrBadIndex) {
+				t.Errorf("Expected ErrBadIndex, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSubAnagrams(t *testing.T) {
+	idx := BuildIndex([]string{"кот", "ток", "кто", "рот", "торт", "тор", "окно", "крот", "о"})
+
+	tests := []struct {
+		name     string
+		letters  string
+		limit    int
+		expected []string
+	}{
+		{
+			name:     "All sub-words ordered by length",
+			letters:  "ткор",
+			expected: []string{"крот", "кот", "кто", "рот", "ток", "тор", "о"},
+		},
+		{
+			name:     "Letters are used no more times than given",
+			letters:  "тот р",
+			expected: []string{"торт", "рот", "тор", "о"},
+		},
+		{
+			name:     "Limit",
+			letters:  "ТКОР",
+			limit:    2,
+			expected: []string{"крот", "кот"},
+		},
+		{
+			name:     "Nothing found",
+			letters:  "аб",
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := idx.SubAnagrams(test.letters, test.limit)
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("Expected %v, but got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestPhraseAnagrams(t *testing.T) {
+	idx := BuildIndex([]string{"кот", "ток", "лес", "сел", "о", "с", "ель", "коса", "сок", "а"})
+
+	tests := []struct {
+		name     string
+		phrase   string
+		opts     PhraseOptions
+		expected [][]string
+	}{
+		{
+			name:   "All phrases",
+			phrase: "кот лес",
+			expected: [][]string{
+				{"лес", "кот"}, {"лес", "ток"}, {"сел", "кот"}, {"сел", "ток"},
+			},
+		},
+		{
+			name:     "Max results",
+			phrase:   "кот лес",
+			opts:     PhraseOptions{MaxResults: 2},
+			expected: [][]string{{"лес", "кот"}, {"лес", "ток"}},
+		},
+		{
+			name:     "Repeated word is not permuted",
+			phrase:   "оо",
+			expected: [][]string{{"о", "о"}},
+		},
+		{
+			name:     "Max words",
+			phrase:   "коса",
+			opts:     PhraseOptions{MaxWords: 2},
+			expected: [][]string{{"коса"}, {"сок", "а"}},
+		},
+		{
+			name:     "Min word length",
+			phrase:   "коса",
+			opts:     PhraseOptions{MinWordLen: 2},
+			expected: [][]string{{"коса"}},
+		},
+		{
+			name:     "Letters can not be covered",
+			phrase:   "кит",
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := idx.PhraseAnagrams(test.phrase, test.opts)
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("Expected %v, but got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+// Перебор идет по копии групп без блокировки: Add и Remove во время поиска не портят результат
+func TestPhraseAnagramsConcurrentUpdates(t *testing.T) {
+	// большая группа: все перестановки букв "абвг"
+	var words []string
+	var permute func(prefix, rest string)
+	permute = func(prefix, rest string) {
+		if rest == "" {
+			words = append(words, prefix)
+			return
+		}
+		for i, r := range []rune(rest) {
+			runes := []rune(rest)
+			permute(prefix+string(r), string(runes[:i])+string(runes[i+1:]))
+		}
+	}
+	permute("", "абвг")
+	idx := BuildIndex(words)
+	valid := make(map[string]bool)
+	for _, w := range words {
+		valid[w] = true
+	}
+
+	stop := make(chan struct{})
+	updated := make(chan struct{})
+	go func() {
+		defer close(updated)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				idx.Remove("абвг")
+				idx.Add("абвг")
+			}
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		for _, phrase := range idx.PhraseAnagrams("абвг абвг", PhraseOptions{}) {
+			if len(phrase) != 2 || !valid[phrase[0]] || !valid[phrase[1]] {
+				t.Fatalf("Unexpected phrase %q", phrase)
+			}
+		}
+	}
+	close(stop)
+	<-updated
+}
+
+func TestFindAnagramsWithOptions(t *testing.T) {
+	// «ёлка» в составной (NFC) и разложенной (NFD, "е" + U+0308) формах
+	nfcYolka := "\u0451лка"
+	nfdYolka := "е\u0308лка"
+
+	tests := []struct {
+		name     string
+		words    []string
+		opts     Options
+		expected map[string][]string
+	}{
+		{
+			name:     "Default options do not touch NFD",
+			words:    []string{nfcYolka, nfdYolka},
+			expected: map[string][]string{},
+		},
+		{
+			name:  "NFC canonicalization",
+			words: []string{nfdYolka, "кал\u0451", nfcYolka},
+			opts:  Options{Form: NormNFC},
+			expected: map[string][]string{
+				nfcYolka: {"кал\u0451", nfcYolka},
+			},
+		},
+		{
+			name:  "NFD canonicalization",
+			words: []string{nfcYolka, "кал\u0451"},
+			opts:  Options{Form: NormNFD},
+			expected: map[string][]string{
+				nfdYolka: {nfdYolka, "кале\u0308"},
+			},
+		},
+		{
+			name:  "Yo folding",
+			words: []string{nfcYolka, "елка", nfdYolka, "кале"},
+			opts:  Options{FoldYo: true},
+			expected: map[string][]string{
+				"елка": {"елка", "кале"},
+			},
+		},
+		{
+			name:  "Letters only",
+			words: []string{"пят-ак", "пятка", "тяп'ка", "--"},
+			opts:  Options{LettersOnly: true},
+			expected: map[string][]string{
+				"пятак": {"пятак", "пятка", "тяпка"},
+			},
+		},
+		{
+			name:  "Keep original spelling",
+			words: []string{"Пят-ак", "пятка", nfdYolka, "елка", nfcYolka},
+			opts:  Options{Form: NormNFC, FoldYo: true, LettersOnly: true, KeepOriginal: true},
+			expected: map[string][]string{
+				nfcYolka: {"елка", nfcYolka},
+				"пят-ак": {"пят-ак", "пятка"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := FindAnagramsWithOptions(&test.words, test.opts)
+			if !reflect.DeepEqual(*result, test.expected) {
+				t.Errorf("Expected %v, but got %v", test.expected, *result)
+			}
+		})
+	}
+}
+
+func TestIndexWithOptions(t *testing.T) {
+	opts := Options{Form: NormNFC, FoldYo: true, LettersOnly: true}
+	idx := BuildIndexWithOptions([]string{"ёлка", "кале", "пят-ак", "тяпка"}, opts)
+
+	expected := []string{"кале"}
+	if result := idx.Anagrams("ёлка"); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, but got %v", expected, result)
+	}
+	if !idx.Contains("пятак") || idx.Add("пя-так") {
+		t.Error("Expected normalized word to be found in index")
+	}
+
+	// настройки нормализации сохраняются вместе с индексом
+	var buf bytes.Buffer
+	if _, err := idx.WriteTo(&buf); err != nil {
+		t.Fatalf("Error writing index: %v", err)
+	}
+	loaded, err := ReadIndex(&buf)
+	if err != nil {
+		t.Fatalf("Error reading index: %v", err)
+	}
+	if loaded.Options() != opts {
+		t.Errorf("Expected options %+v, got %+v", opts, loaded.Options())
+	}
+	if !reflect.DeepEqual(loaded.groups, idx.groups) {
+		t.Errorf("Expected %v, but got %v", idx.groups, loaded.groups)
+	}
+}
+
+func TestReadIndexVersion1(t *testing.T) {
+	// индекс первой версии: без байта настроек, одна группа "акптя" -> [пятак, пятка]
+	var data []byte
+	data = append(data, "ANIX\x01\x01"...)
+	for _, s := range []string{"акптя"} {
+		data = append(data, byte(len(s)))
+		data = append(data, s...)
+	}
+	data = append(data, 2)
+	for _, s := range []string{"пятак", "пятка"} {
+		data = append(data, byte(len(s)))
+		data = append(data, s...)
+	}
+
+	idx, err := ReadIndex(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Error reading index: %v", err)
+	}
+	expected := []string{"пятка"}
+	if result := idx.Anagrams("пятак"); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, but got %v", expected, result)
+	}
+	if idx.Options() != (Options{}) {
+		t.Errorf("Expected default options, got %+v", idx.Options())
+	}
+}
+
+// Функция генерирует словарь из перестановок случайных слов, чтобы в нем было много групп анаграмм
+func generateWords(n int) []string {
+	letters := []rune("абвгдежзийклмнопрстуфхцчшщыэюяАБВГ")
+	rnd := rand.New(rand.NewSource(1))
+
+	words := make([]string, 0, n)
+	for len(words) < n {
+		base := make([]rune, 3+rnd.Intn(6))
+		for i := range base {
+			base[i] = letters[rnd.Intn(len(letters))]
+		}
+		// несколько перестановок одного слова, включая повторы
+		for k := 0; k < 1+rnd.Intn(4) && len(words) < n; k++ {
+			rnd.Shuffle(len(base), func(i, j int) { base[i], base[j] = base[j], base[i] })
+			words = append(words, string(base))
+		}
+	}
+	return words
+}
+
+func TestFindAnagramsParallel(t *testing.T) {
+	words := generateWords(20000)
+	expected := FindAnagrams(&words)
+	if len(*expected) == 0 {
+		t.Fatal("Expected generated dictionary to contain anagrams")
+	}
+
+	for _, workers := range []int{0, 1, 2, 3, 8, 64} {
+		result := FindAnagramsParallel(&words, workers)
+		if !reflect.DeepEqual(*result, *expected) {
+			t.Errorf("Parallel result with %d workers differs from sequential", workers)
+		}
+	}
+
+	opts := Options{Form: NormNFC, FoldYo: true, LettersOnly: true, KeepOriginal: true}
+	mixed := []string{"Пят-ак", "пятка", "ёлка", "елка", "ёлка", "тяпка", "пятак"}
+	expected = FindAnagramsWithOptions(&mixed, opts)
+	if result := FindAnagramsParallelWithOptions(&mixed, opts, 3); !reflect.DeepEqual(*result, *expected) {
+		t.Errorf("Expected %v, but got %v", *expected, *result)
+	}
+
+	var empty []string
+	if result := FindAnagramsParallel(&empty, 4); len(*result) != 0 {
+		t.Errorf("Expected empty result, got %v", *result)
+	}
+}
+
+func BenchmarkFindAnagrams(b *testing.B) {
+	words := generateWords(200000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FindAnagrams(&words)
+	}
+}
+
+func BenchmarkFindAnagramsParallel(b *testing.B) {
+	words := generateWords(200000)
+	for _, workers := range []int{2, 4, runtime.GOMAXPROCS(0)} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				FindAnagramsParallel(&words, workers)
+			}
+		})
+	}
+}
+
+func TestFindAnagramsKeyPolicy(t *testing.T) {
+	words := []string{"тяпка", "слово", "пятак", "ловос", "Пятка"}
+
+	tests := []struct {
+		name     string
+		policy   KeyPolicy
+		expected map[string][]string
+	}{
+		{
+			name:   "First seen",
+			policy: KeyFirstSeen,
+			expected: map[string][]string{
+				"тяпка": {"пятак", "пятка", "тяпка"},
+				"слово": {"ловос", "слово"},
+			},
+		},
+		{
+			name:   "Alphabetical",
+			policy: KeyAlphabetical,
+			expected: map[string][]string{
+				"пятак": {"пятак", "пятка", "тяпка"},
+				"ловос": {"ловос", "слово"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := FindAnagramsWithOptions(&words, Options{Key: test.policy})
+			if !reflect.DeepEqual(*result, test.expected) {
+				t.Errorf("Expected %v, but got %v", test.expected, *result)
+			}
+			result = FindAnagramsParallelWithOptions(&words, Options{Key: test.policy}, 2)
+			if !reflect.DeepEqual(*result, test.expected) {
+				t.Errorf("Parallel: expected %v, but got %v", test.expected, *result)
+			}
+		})
+	}
+}
+
+func TestOrderedGroups(t *testing.T) {
+	groups := map[string][]string{
+		"слово":  {"ловос", "слово"},
+		"тяпка":  {"пятак", "пятка", "тяпка"},
+		"листок": {"листок", "слиток", "столик"},
+		"кот":    {"кот", "ток"},
+	}
+
+	byKey := OrderedGroups(groups, ByKey)
+	expected := []Group{
+		{"кот", []string{"кот", "ток"}},
+		{"листок", []string{"листок", "слиток", "столик"}},
+		{"слово", []string{"ловос", "слово"}},
+		{"тяпка", []string{"пятак", "пятка", "тяпка"}},
+	}
+	if !reflect.DeepEqual(byKey, expected) {
+		t.Errorf("Expected %v, but got %v", expected, byKey)
+	}
+
+	bySize := OrderedGroups(groups, BySize)
+	expected = []Group{
+		{"листок", []string{"листок", "слиток", "столик"}},
+		{"тяпка", []string{"пятак", "пятка", "тяпка"}},
+		{"кот", []string{"кот", "ток"}},
+		{"слово", []string{"ловос", "слово"}},
+	}
+	if !reflect.DeepEqual(bySize, expected) {
+		t.Errorf("Expected %v, but got %v", expected, bySize)
+	}
+
+	var buf bytes.Buffer
+	if err := PrintGroups(&buf, bySize); err != nil {
+		t.Fatalf("Error printing groups: %v", err)
+	}
+	expectedOutput := "листок: листок, слиток, столик\nтяпка: пятак, пятка, тяпка\nкот: кот, ток\nслово: ловос, слово\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Expected output:\n%s\nGot:\n%s", expectedOutput, buf.String())
+	}
+}
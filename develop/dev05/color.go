@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Значения --color
+const (
+	colorAuto   = "auto"   // цвет, только если вывод идет в терминал
+	colorAlways = "always" // цвет всегда
+	colorNever  = "never"  // без цвета
+)
+
+// colorFlag - значение --color. Флаг можно указать без значения, тогда он означает auto
+type colorFlag string
+
+func (f *colorFlag) String() string {
+	return string(*f)
+}
+
+func (f *colorFlag) Set(value string) error {
+	switch value {
+	case "true":
+		value = colorAuto
+	case "false":
+		value = colorNever
+	case "", colorAuto, colorAlways, colorNever:
+	case "yes", "force":
+		value = colorAlways
+	case "no", "none":
+		value = colorNever
+	case "tty", "if-tty":
+		value = colorAuto
+	default:
+		return fmt.Errorf("invalid --color value %q", value)
+	}
+	*f = colorFlag(value)
+	return nil
+}
+
+// IsBoolFlag позволяет писать --color без значения
+func (f *colorFlag) IsBoolFlag() bool {
+	return true
+}
+
+// Функция сообщает, нужен ли цвет при выводе: в терминал (если он не dumb) для auto
+func (f colorFlag) enabled(terminal bool) bool {
+	switch f {
+	case colorAlways:
+		return true
+	case colorAuto:
+		return terminal && os.Getenv("TERM") != "dumb"
+	}
+	return false
+}
+
+// colorScheme - параметры SGR для частей вывода в формате GREP_COLORS
+type colorScheme struct {
+	selectedMatch string // ms - совпадение в выбранной строке
+	contextMatch  string // mc - совпадение в строке контекста
+	selectedLine  string // sl - вся выбранная строка
+	contextLine   string // cx - вся строка контекста
+	fileName      string // fn - имя файла
+	lineNum       string // ln - номер строки
+	byteOffset    string // bn - смещение в байтах
+	separator     string // se - разделители ':', '-' и разделитель групп
+	reverse       bool   // rv - поменять sl и cx местами при -v
+	noClear       bool   // ne - не дописывать \x1b[K после каждой раскраски
+}
+
+// Функция возвращает цвета GNU grep по умолчанию
+func defaultColors() *colorScheme {
+	return &colorScheme{
+		selectedMatch: "01;31",
+		contextMatch:  "01;31",
+		fileName:      "35",
+		lineNum:       "32",
+		byteOffset:    "32",
+		separator:     "36",
+	}
+}
+
+// Функция разбирает GREP_COLORS поверх цветов по умолчанию.
+// Неизвестные и некорректные элементы игнорируются, как в GNU grep
+func parseGrepColors(env string) *colorScheme {
+	c := defaultColors()
+	if env == "" {
+		return c
+	}
+
+	for _, item := range strings.Split(env, ":") {
+		name, value, hasValue := strings.Cut(item, "=")
+		if hasValue && strings.Trim(value, "0123456789;") != "" {
+			continue
+		}
+
+		switch name {
+		case "mt":
+			c.selectedMatch, c.contextMatch = value, value
+		case "ms":
+			c.selectedMatch = value
+		case "mc":
+			c.contextMatch = value
+		case "sl":
+			c.selectedLine = value
+		case "cx":
+			c.contextLine = value
+		case "fn":
+			c.fileName = value
+		case "ln":
+			c.lineNum = value
+		case "bn":
+			c.byteOffset = value
+		case "se":
+			c.separator = value
+		case "rv":
+			c.reverse = !hasValue
+		case "ne":
+			c.noClear = !hasValue
+		}
+	}
+	return c
+}
+
+// Функция возвращает цвета строк с учетом rv: с -v выбранными становятся строки без совпадений
+func (c *colorScheme) lineColors(invert bool) (selected, context string) {
+	if c.reverse && invert {
+		return c.contextLine, c.selectedLine
+	}
+	return c.selectedLine, c.contextLine
+}
+
+// Функция возвращает последовательность, включающую цвет sgr
+func (c *colorScheme) start(sgr string) string {
+	if c.noClear {
+		return "\x1b[" + sgr + "m"
+	}
+	return "\x1b[" + sgr + "m\x1b[K"
+}
+
+// Функция возвращает последовательность, сбрасывающую цвет
+func (c *colorScheme) end() string {
+	if c.noClear {
+		return "\x1b[m"
+	}
+	return "\x1b[m\x1b[K"
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Без учета регистра руны сравниваются по простому свертыванию Unicode (unicode.SimpleFold),
+// как в (?i) пакета regexp: «σ», «ς» и «Σ», «ß» и «ẞ», «k», «K» и знак кельвина «K» равны.
+// Свертывание не зависит от локали: турецкие «ı» и «İ» не равны «i» и «I»
+
+// Функция возвращает представителя класса свертывания руны - наименьшую руну орбиты SimpleFold
+func foldRune(r rune) rune {
+	folded := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < folded {
+			folded = f
+		}
+	}
+	return folded
+}
+
+// Функция приводит строку к виду, в котором строки, равные без учета регистра, совпадают
+func foldString(s string) string {
+	return strings.Map(foldRune, s)
+}
+
+// foldMatcher - поиск фиксированных строк без учета регистра (-F -i).
+// Строка при этом не меняется, поэтому границы совпадений указывают на исходный текст
+type foldMatcher struct {
+	patterns   [][]rune // паттерны из свернутых рун
+	matchEmpty bool     // пустой паттерн совпадает с любой строкой
+}
+
+// Функция создает matcher для фиксированных строк без учета регистра
+func newFoldMatcher(patterns []string) foldMatcher {
+	var m foldMatcher
+	for _, p := range patterns {
+		if p == "" {
+			m.matchEmpty = true
+		} else {
+			m.patterns = append(m.patterns, []rune(foldString(p)))
+		}
+	}
+	return m
+}
+
+func (m foldMatcher) match(line string) bool {
+	if m.matchEmpty {
+		return true
+	}
+	for pos := 0; pos < len(line); {
+		for _, p := range m.patterns {
+			if _, ok := matchFoldedAt(line, pos, p); ok {
+				return true
+			}
+		}
+		_, size := utf8.DecodeRuneInString(line[pos:])
+		pos += size
+	}
+	return false
+}
+
+func (m foldMatcher) find(line string) [][]int {
+	var spans [][]int
+	for pos := 0; pos < len(line); {
+		// самое левое совпадение, при равенстве - самое длинное
+		end := -1
+		for _, p := range m.patterns {
+			if e, ok := matchFoldedAt(line, pos, p); ok && e > end {
+				end = e
+			}
+		}
+		if end >= 0 {
+			spans = append(spans, []int{pos, end})
+			pos = end
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(line[pos:])
+		pos += size
+	}
+	return spans
+}
+
+// Функция выбирает matcher для фиксированных строк без учета регистра (-F -i): непустые паттерны
+// сворачиваются и ищутся в свернутой копии строки так же быстро, как без -i - алгоритмом
+// Бойера-Мура-Хорспула или автоматом Ахо-Корасик
+func newFoldFixedMatcher(patterns []string) matcher {
+	fm := newFoldMatcher(patterns)
+	if fm.matchEmpty || len(fm.patterns) == 0 {
+		return fm
+	}
+	folded := make([]string, len(fm.patterns))
+	for i, p := range fm.patterns {
+		folded[i] = string(p)
+	}
+	return foldedMatcher{inner: newFixedMatcher(folded)}
+}
+
+// foldedMatcher - поиск свернутых паттернов в свернутой копии строки.
+// Свертывание может менять длину руны в байтах (знак кельвина «K» становится «K»),
+// поэтому границы совпадений переводятся обратно в байты исходной строки
+type foldedMatcher struct {
+	inner matcher
+}
+
+func (m foldedMatcher) match(line string) bool {
+	return m.inner.match(foldString(line))
+}
+
+func (m foldedMatcher) find(line string) [][]int {
+	folded, offsets := foldWithOffsets(line)
+	spans := m.inner.find(folded)
+	// свернутые паттерны - корректный UTF-8, поэтому совпадения начинаются и заканчиваются на границах рун
+	for _, span := range spans {
+		span[0], span[1] = offsets[span[0]], offsets[span[1]]
+	}
+	return spans
+}
+
+// Функция сворачивает строку, как foldString, и возвращает для каждого байта свернутой строки
+// и для ее конца байт исходной строки, с которого начинается соответствующая руна
+func foldWithOffsets(line string) (string, []int) {
+	var sb strings.Builder
+	sb.Grow(len(line))
+	offsets := make([]int, 0, len(line)+1)
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		n, _ := sb.WriteRune(foldRune(r))
+		for ; n > 0; n-- {
+			offsets = append(offsets, i)
+		}
+		i += size
+	}
+	return sb.String(), append(offsets, len(line))
+}
+
+// Функция сравнивает свернутый паттерн с текстом строки, начиная с байта pos,
+// и возвращает байт, на котором закончилось совпадение
+func matchFoldedAt(line string, pos int, p []rune) (int, bool) {
+	for _, want := range p {
+		if pos >= len(line) {
+			return 0, false
+		}
+		r, size := utf8.DecodeRuneInString(line[pos:])
+		if foldRune(r) != want {
+			return 0, false
+		}
+		pos += size
+	}
+	return pos, true
+}
+
+// foldLineMatcher - сравнение всей строки с фиксированными строками без учета регистра (-F -x -i)
+type foldLineMatcher map[string]struct{}
+
+// Функция создает matcher для сравнения строки целиком без учета регистра
+func newFoldLineMatcher(patterns []string) foldLineMatcher {
+	m := make(foldLineMatcher, len(patterns))
+	for _, p := range patterns {
+		m[foldString(p)] = struct{}{}
+	}
+	return m
+}
+
+func (m foldLineMatcher) match(line string) bool {
+	_, ok := m[foldString(line)]
+	return ok
+}
+
+func (m foldLineMatcher) find(line string) [][]int {
+	if m.match(line) {
+		return [][]int{{0, len(line)}}
+	}
+	return nil
+}
+
+// Функция решает, искать ли без учета регистра: с -i всегда, с --smart-case -
+// если в паттернах нет заглавных букв
+func (options grepOptions) foldCase(patterns []string) bool {
+	if options.ignoreCase {
+		return true
+	}
+	if !options.smartCase {
+		return false
+	}
+	for _, p := range patterns {
+		if hasUpper(p, options.fixed) {
+			return false
+		}
+	}
+	return true
+}
+
+// Функция ищет заглавные буквы в паттерне. В регулярном выражении экранированные
+// последовательности (\W, \S, \p{Lu}) не считаются - это не буквы текста
+func hasUpper(p string, literal bool) bool {
+	for i := 0; i < len(p); {
+		r, size := utf8.DecodeRuneInString(p[i:])
+		i += size
+		if r == '\\' && !literal && i < len(p) {
+			next := p[i]
+			i++
+			if (next == 'p' || next == 'P') && i < len(p) && p[i] == '{' {
+				if end := strings.IndexByte(p[i:], '}'); end >= 0 {
+					i += end + 1
+				}
+			}
+			continue
+		}
+		if unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+)
+
+// Вывод --json: одно событие JSON на строку, в духе формата JSON Lines у ripgrep.
+// Для каждого файла с совпадениями печатаются begin, match и context для строк, end со статистикой,
+// в конце поиска - summary с общей статистикой
+
+// jsonEvent - одно событие вывода
+type jsonEvent struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// jsonText - строка из входа: текст в UTF-8 или, если она не является корректным UTF-8, байты в base64
+type jsonText struct {
+	Text  *string `json:"text,omitempty"`
+	Bytes *string `json:"bytes,omitempty"`
+}
+
+// Функция кодирует строку из входа
+func newJSONText(s string) jsonText {
+	if utf8.ValidString(s) {
+		return jsonText{Text: &s}
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(s))
+	return jsonText{Bytes: &encoded}
+}
+
+// jsonBegin - начало вывода по файлу
+type jsonBegin struct {
+	Path jsonText `json:"path"`
+}
+
+// jsonLine - строка с совпадением (match) или строка контекста (context)
+type jsonLine struct {
+	Path           jsonText       `json:"path"`
+	Lines          jsonText       `json:"lines"`
+	LineNumber     int            `json:"line_number"`
+	AbsoluteOffset int            `json:"absolute_offset"`
+	Submatches     []jsonSubmatch `json:"submatches"`
+}
+
+// jsonSubmatch - одно совпадение в строке, start и end - смещения в байтах от начала строки
+type jsonSubmatch struct {
+	Match jsonText `json:"match"`
+	Start int      `json:"start"`
+	End   int      `json:"end"`
+}
+
+// jsonEnd - конец вывода по файлу
+type jsonEnd struct {
+	Path   jsonText  `json:"path"`
+	Binary bool      `json:"binary"`
+	Stats  jsonStats `json:"stats"`
+}
+
+// jsonSummary - итог поиска по всем файлам
+type jsonSummary struct {
+	ElapsedTotal jsonDuration `json:"elapsed_total"`
+	Stats        jsonStats    `json:"stats"`
+}
+
+// jsonStats - статистика поиска по файлу или по всем файлам
+type jsonStats struct {
+	Elapsed           jsonDuration `json:"elapsed"`
+	Searches          int          `json:"searches"`
+	SearchesWithMatch int          `json:"searches_with_match"`
+	BytesSearched     int          `json:"bytes_searched"`
+	MatchedLines      int          `json:"matched_lines"`
+	Matches           int          `json:"matches"`
+}
+
+// Функция добавляет к статистике статистику другого поиска
+func (s *jsonStats) add(other jsonStats) {
+	s.Elapsed = newJSONDuration(s.Elapsed.duration() + other.Elapsed.duration())
+	s.Searches += other.Searches
+	s.SearchesWithMatch += other.SearchesWithMatch
+	s.BytesSearched += other.BytesSearched
+	s.MatchedLines += other.MatchedLines
+	s.Matches += other.Matches
+}
+
+// jsonDuration - продолжительность в секундах и наносекундах и в читаемом виде
+type jsonDuration struct {
+	Secs  int64  `json:"secs"`
+	Nanos int64  `json:"nanos"`
+	Human string `json:"human"`
+}
+
+// Функция переводит продолжительность в jsonDuration
+func newJSONDuration(d time.Duration) jsonDuration {
+	return jsonDuration{
+		Secs:  int64(d / time.Second),
+		Nanos: int64(d % time.Second),
+		Human: d.String(),
+	}
+}
+
+// Функция переводит jsonDuration обратно в time.Duration
+func (d jsonDuration) duration() time.Duration {
+	return time.Duration(d.Secs)*time.Second + time.Duration(d.Nanos)
+}
+
+// event печатает одно событие
+func (p *printer) event(eventType string, data any) error {
+	b, err := json.Marshal(jsonEvent{Type: eventType, Data: data})
+	if err != nil {
+		return err
+	}
+	p.w.Write(b)
+	return p.endLine()
+}
+
+// beginFile печатает begin перед первой строкой файла
+func (p *printer) beginFile(name string) error {
+	if p.began {
+		return nil
+	}
+	p.began = true
+	return p.event("begin", jsonBegin{Path: newJSONText(name)})
+}
+
+// jsonLine печатает событие match или context
+func (p *printer) jsonLine(name string, l bufferedLine, sep byte, spans [][]int) error {
+	if err := p.beginFile(name); err != nil {
+		return err
+	}
+
+	eventType := "match"
+	if sep == contextSep {
+		eventType = "context"
+	}
+	data := jsonLine{
+		Path:           newJSONText(name),
+		Lines:          newJSONText(l.text),
+		LineNumber:     l.num,
+		AbsoluteOffset: l.offset,
+		Submatches:     []jsonSubmatch{},
+	}
+	for _, span := range spans {
+		if span[0] == span[1] {
+			continue
+		}
+		data.Submatches = append(data.Submatches, jsonSubmatch{
+			Match: newJSONText(l.text[span[0]:span[1]]),
+			Start: span[0],
+			End:   span[1],
+		})
+	}
+	if sep == matchSep {
+		p.file.Matches += len(data.Submatches)
+	}
+	return p.event(eventType, data)
+}
+
+// endFile печатает end, если по файлу что-то было напечатано, и добавляет статистику файла к общей
+func (p *printer) endFile(name string, matches, size int, elapsed time.Duration) error {
+	stats := p.file
+	stats.Elapsed = newJSONDuration(elapsed)
+	stats.Searches = 1
+	stats.BytesSearched = size
+	stats.MatchedLines = matches
+	if matches > 0 {
+		stats.SearchesWithMatch = 1
+	}
+	p.total.add(stats)
+
+	began, binary := p.began, p.binary
+	p.file, p.began, p.binary = jsonStats{}, false, false
+	if !began {
+		return nil
+	}
+	return p.event("end", jsonEnd{Path: newJSONText(name), Binary: binary, Stats: stats})
+}
+
+// summary печатает итог поиска по всем файлам
+func (p *printer) summary(elapsed time.Duration) error {
+	return p.event("summary", jsonSummary{ElapsedTotal: newJSONDuration(elapsed), Stats: p.total})
+}
@@ -0,0 +1,195 @@
+package main
+
+// Быстрый поиск фиксированных строк (-F): один паттерн ищется алгоритмом Бойера-Мура-Хорспула,
+// несколько - автоматом Ахо-Корасик за один проход по строке независимо от числа паттернов
+
+// Функция выбирает matcher для фиксированных строк с учетом регистра
+func newFixedMatcher(patterns []string) matcher {
+	lm := newLiteralMatcher(patterns)
+	switch {
+	case lm.matchEmpty || len(lm.patterns) == 0:
+		return lm
+	case len(lm.patterns) == 1:
+		return newHorspoolMatcher(lm.patterns[0])
+	}
+	return newAhoCorasick(lm.patterns)
+}
+
+// horspoolMatcher - поиск одной фиксированной строки алгоритмом Бойера-Мура-Хорспула
+type horspoolMatcher struct {
+	pattern string
+	shift   [256]int // сдвиг по последнему байту окна
+}
+
+// Функция создает matcher для непустой фиксированной строки
+func newHorspoolMatcher(pattern string) *horspoolMatcher {
+	m := &horspoolMatcher{pattern: pattern}
+	for i := range m.shift {
+		m.shift[i] = len(pattern)
+	}
+	for i := 0; i < len(pattern)-1; i++ {
+		m.shift[pattern[i]] = len(pattern) - 1 - i
+	}
+	return m
+}
+
+// Функция возвращает позицию первого вхождения паттерна в line, начиная с from, или -1
+func (m *horspoolMatcher) index(line string, from int) int {
+	n := len(m.pattern)
+	last := m.pattern[n-1]
+	for pos := from; pos+n <= len(line); {
+		c := line[pos+n-1]
+		if c == last && line[pos:pos+n-1] == m.pattern[:n-1] {
+			return pos
+		}
+		pos += m.shift[c]
+	}
+	return -1
+}
+
+func (m *horspoolMatcher) match(line string) bool {
+	return m.index(line, 0) >= 0
+}
+
+func (m *horspoolMatcher) find(line string) [][]int {
+	var spans [][]int
+	for pos := 0; ; {
+		i := m.index(line, pos)
+		if i < 0 {
+			break
+		}
+		pos = i + len(m.pattern)
+		spans = append(spans, []int{i, pos})
+	}
+	return spans
+}
+
+// acNode - состояние автомата Ахо-Корасик
+type acNode struct {
+	keys    []byte  // байты переходов
+	targets []int32 // состояния переходов, в порядке keys
+	fail    int32   // самый длинный собственный суффикс, который тоже есть в боре
+	output  int32   // длина самого длинного паттерна, который заканчивается в этом состоянии, 0 - нет
+	depth   int32   // длина пути от корня
+}
+
+// Функция возвращает переход по байту c или -1
+func (n *acNode) child(c byte) int32 {
+	for i, k := range n.keys {
+		if k == c {
+			return n.targets[i]
+		}
+	}
+	return -1
+}
+
+// ahoCorasick - поиск нескольких фиксированных строк автоматом Ахо-Корасик.
+// Переходы из корня хранятся таблицей, остальные - короткими списками: у большинства состояний
+// всего один-два перехода, а таблица на каждое состояние заняла бы слишком много памяти
+type ahoCorasick struct {
+	nodes []acNode
+	root  [256]int32 // переходы из корня с учетом ссылок неудач: всегда >= 0
+}
+
+// Функция строит автомат по непустым паттернам
+func newAhoCorasick(patterns []string) *ahoCorasick {
+	ac := &ahoCorasick{nodes: []acNode{{}}}
+
+	// бор
+	for _, p := range patterns {
+		state := int32(0)
+		for i := 0; i < len(p); i++ {
+			next := ac.nodes[state].child(p[i])
+			if next < 0 {
+				next = int32(len(ac.nodes))
+				ac.nodes = append(ac.nodes, acNode{depth: ac.nodes[state].depth + 1})
+				ac.nodes[state].keys = append(ac.nodes[state].keys, p[i])
+				ac.nodes[state].targets = append(ac.nodes[state].targets, next)
+			}
+			state = next
+		}
+		ac.nodes[state].output = int32(len(p))
+	}
+
+	// ссылки неудач обходом в ширину: у состояний меньшей глубины они уже посчитаны
+	for c := range ac.root {
+		ac.root[c] = max(ac.nodes[0].child(byte(c)), 0)
+	}
+	queue := append([]int32(nil), ac.nodes[0].targets...)
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		node := &ac.nodes[state]
+		if node.output == 0 {
+			// паттерн, который заканчивается в суффиксе, заканчивается и здесь
+			node.output = ac.nodes[node.fail].output
+		}
+		for i, c := range node.keys {
+			child := node.targets[i]
+			if state != 0 {
+				ac.nodes[child].fail = ac.next(node.fail, c)
+			}
+			queue = append(queue, child)
+		}
+	}
+	return ac
+}
+
+// Функция возвращает состояние после байта c
+func (ac *ahoCorasick) next(state int32, c byte) int32 {
+	for state != 0 {
+		if next := ac.nodes[state].child(c); next >= 0 {
+			return next
+		}
+		state = ac.nodes[state].fail
+	}
+	return ac.root[c]
+}
+
+func (ac *ahoCorasick) match(line string) bool {
+	state := int32(0)
+	for i := 0; i < len(line); i++ {
+		state = ac.next(state, line[i])
+		if ac.nodes[state].output > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// find возвращает самые левые, а среди них самые длинные непересекающиеся совпадения, как literalMatcher
+func (ac *ahoCorasick) find(line string) [][]int {
+	var spans [][]int
+	for pos := 0; pos < len(line); {
+		start, end := ac.leftmostLongest(line, pos)
+		if start < 0 {
+			break
+		}
+		spans = append(spans, []int{start, end})
+		pos = end
+	}
+	return spans
+}
+
+// Функция ищет в line, начиная с pos, самое левое совпадение, а среди них самое длинное.
+// Возвращает -1, если совпадений нет
+func (ac *ahoCorasick) leftmostLongest(line string, pos int) (int, int) {
+	start, end := -1, -1
+	state := int32(0)
+	for i := pos; i < len(line); i++ {
+		state = ac.next(state, line[i])
+		node := &ac.nodes[state]
+		// дальнейшие совпадения начнутся не раньше начала текущего пути
+		if start >= 0 && i+1-int(node.depth) > start {
+			break
+		}
+		// самый длинный паттерн, заканчивающийся здесь, начинается левее всех
+		if node.output > 0 {
+			s := i + 1 - int(node.output)
+			if start < 0 || s <= start {
+				start, end = s, i+1
+			}
+		}
+	}
+	return start, end
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// matcher ищет совпадения паттернов в строке
+type matcher interface {
+	// match сообщает, есть ли в строке совпадение
+	match(line string) bool
+	// find возвращает границы всех непересекающихся совпадений в строке
+	find(line string) [][]int
+}
+
+// Функция строит matcher по паттернам и флагам -G/-E/-P/-F, -i, --smart-case, -w, -x
+func newMatcher(options grepOptions) (matcher, error) {
+	modes := 0
+	for _, set := range []bool{options.basic, options.extended, options.perl, options.fixed} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return nil, errors.New("conflicting matchers specified")
+	}
+
+	patterns := options.patternList()
+	options.ignoreCase = options.foldCase(patterns)
+
+	var m, whole matcher
+	switch {
+	case options.fixed && options.lineRegexp && options.ignoreCase:
+		return newFoldLineMatcher(patterns), nil
+	case options.fixed && options.lineRegexp:
+		return newLineMatcher(patterns), nil
+	case options.fixed && options.ignoreCase:
+		m, whole = newFoldFixedMatcher(patterns), newFoldLineMatcher(patterns)
+	case options.fixed:
+		m, whole = newFixedMatcher(patterns), newLineMatcher(patterns)
+	default:
+		re, err := compilePatterns(patterns, options)
+		if err != nil {
+			return nil, err
+		}
+		m = regexMatcher{re: re}
+		if options.wordRegexp {
+			// то же выражение, но целиком: для проверки более коротких совпадений -w
+			lineOptions := options
+			lineOptions.lineRegexp = true
+			if re, err = compilePatterns(patterns, lineOptions); err != nil {
+				return nil, err
+			}
+			whole = regexMatcher{re: re}
+		}
+	}
+
+	if options.wordRegexp && !options.lineRegexp {
+		m = wordMatcher{inner: m, whole: whole}
+	}
+	return m, nil
+}
+
+// Функция возвращает список паттернов: -e и -f, а если их нет - позиционный паттерн.
+// Паттерн с переводами строк, как в GNU grep, задает несколько паттернов
+func (options grepOptions) patternList() []string {
+	patterns := options.patterns
+	if len(patterns) == 0 && !options.patternsSet {
+		patterns = []string{options.pattern}
+	}
+
+	var result []string
+	for _, p := range patterns {
+		result = append(result, strings.Split(p, "\n")...)
+	}
+	return result
+}
+
+// Функция собирает паттерны в одно регулярное выражение
+func compilePatterns(patterns []string, options grepOptions) (*regexp.Regexp, error) {
+	// пустой список паттернов (например, пустой файл -f) не совпадает ни с чем
+	if len(patterns) == 0 {
+		return regexp.MustCompile(`[^\x00-\x{10FFFF}]`), nil
+	}
+
+	parts := make([]string, len(patterns))
+	for i, p := range patterns {
+		var err error
+		switch {
+		case options.fixed:
+			parts[i] = regexp.QuoteMeta(p)
+		case options.perl:
+			parts[i] = p
+		default:
+			parts[i], err = translatePOSIX(p, !options.extended)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+
+	expr := "(?:" + strings.Join(parts, ")|(?:") + ")"
+	if options.lineRegexp {
+		expr = "^(?:" + expr + ")$"
+	}
+	if options.ignoreCase {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	// POSIX grep выбирает самое длинное из самых левых совпадений, -P - первое найденное, как Perl
+	if !options.perl {
+		re.Longest()
+	}
+	return re, nil
+}
+
+// Функция переводит базовое (BRE) или расширенное (ERE) регулярное выражение POSIX
+// с расширениями GNU в синтаксис пакета regexp
+func translatePOSIX(p string, basic bool) (string, error) {
+	var sb strings.Builder
+	// atStart - позиция, где * буквальна, а ^ в BRE является якорем: начало выражения, после ( или |
+	atStart := true
+
+	for i := 0; i < len(p); {
+		c := p[i]
+		wasStart := atStart
+		atStart = false
+
+		switch {
+		case c == '\\':
+			if i+1 == len(p) {
+				return "", errors.New("trailing backslash")
+			}
+			next := p[i+1]
+			i += 2
+			switch {
+			case basic && strings.IndexByte("(){}|+?", next) >= 0:
+				// в BRE экранированные скобки и операторы - это операторы
+				sb.WriteByte(next)
+				atStart = next == '(' || next == '|'
+				writeIntervalStart(&sb, next, p[i:])
+			case next == '<' || next == '>':
+				sb.WriteString(`\b`)
+			case next == '`':
+				sb.WriteString(`\A`)
+			case next == '\'':
+				sb.WriteString(`\z`)
+			case next >= '1' && next <= '9':
+				return "", errors.New("back-references are not supported")
+			case strings.IndexByte("bBwWsS", next) >= 0:
+				sb.WriteByte('\\')
+				sb.WriteByte(next)
+			default:
+				// остальные экранированные символы буквальны
+				r, size := utf8.DecodeRuneInString(p[i-1:])
+				sb.WriteString(regexp.QuoteMeta(string(r)))
+				i += size - 1
+			}
+		case c == '[':
+			n, err := translateBracket(&sb, p[i:])
+			if err != nil {
+				return "", err
+			}
+			i += n
+		case c == '*' && wasStart:
+			sb.WriteString(`\*`)
+			i++
+		case basic && strings.IndexByte("(){}|+?", c) >= 0:
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+			i++
+		case basic && c == '^' && !wasStart:
+			sb.WriteString(`\^`)
+			i++
+		case basic && c == '$' && !atBREEnd(p[i+1:]):
+			sb.WriteString(`\$`)
+			i++
+		default:
+			if c == '^' {
+				atStart = true
+			}
+			if !basic && (c == '(' || c == '|') {
+				atStart = true
+			}
+			sb.WriteByte(c)
+			i++
+			if !basic {
+				writeIntervalStart(&sb, c, p[i:])
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// Функция дописывает нижнюю границу 0 к интервалу GNU {,n}, если c - открывающая скобка
+// интервала, а rest - то, что идет после нее: regexp считает такой интервал обычным текстом
+func writeIntervalStart(sb *strings.Builder, c byte, rest string) {
+	if c == '{' && strings.HasPrefix(rest, ",") {
+		sb.WriteByte('0')
+	}
+}
+
+// Функция сообщает, является ли $ перед rest якорем конца в BRE: в конце выражения, перед \) или \|
+func atBREEnd(rest string) bool {
+	return rest == "" || strings.HasPrefix(rest, `\)`) || strings.HasPrefix(rest, `\|`)
+}
+
+// unicodeClasses - замена классов POSIX, которые должны учитывать не только ASCII
+var unicodeClasses = map[string]string{
+	"[:alpha:]": `\p{L}`,
+	"[:alnum:]": `\p{L}\p{Nd}`,
+	"[:upper:]": `\p{Lu}`,
+	"[:lower:]": `\p{Ll}`,
+}
+
+// Функция переводит скобочное выражение POSIX, с которого начинается p, и возвращает число
+// прочитанных байт. Внутри скобок обратная косая черта в POSIX буквальна, а в regexp - нет
+func translateBracket(sb *strings.Builder, p string) (int, error) {
+	sb.WriteByte('[')
+	i := 1
+	if i < len(p) && p[i] == '^' {
+		sb.WriteByte('^')
+		i++
+	}
+	// ] сразу после [ или [^ - обычный символ
+	if i < len(p) && p[i] == ']' {
+		sb.WriteString(`\]`)
+		i++
+	}
+
+	for i < len(p) {
+		c := p[i]
+		switch {
+		case c == ']':
+			sb.WriteByte(']')
+			return i + 1, nil
+		case c == '[' && i+1 < len(p) && p[i+1] == ':':
+			end := strings.Index(p[i+2:], ":]")
+			if end < 0 {
+				return 0, errors.New("unterminated character class")
+			}
+			// буквенные классы в regexp только ASCII, а grep в UTF-8 понимает любые буквы
+			class := p[i : i+2+end+2]
+			if unicodeClass, ok := unicodeClasses[class]; ok {
+				class = unicodeClass
+			}
+			sb.WriteString(class)
+			i += 2 + end + 2
+		case c == '[' && i+1 < len(p) && (p[i+1] == '=' || p[i+1] == '.'):
+			// классы эквивалентности и сопоставляющие элементы сводим к самому символу
+			closing := string([]byte{p[i+1], ']'})
+			end := strings.Index(p[i+2:], closing)
+			if end < 0 {
+				return 0, errors.New("unterminated equivalence class")
+			}
+			sb.WriteString(regexp.QuoteMeta(p[i+2 : i+2+end]))
+			i += 2 + end + 2
+		case c == '\\' || c == '[':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+			i++
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return 0, errors.New("unmatched [")
+}
+
+// regexMatcher - поиск по регулярному выражению
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m regexMatcher) match(line string) bool {
+	return m.re.MatchString(line)
+}
+
+func (m regexMatcher) find(line string) [][]int {
+	return m.re.FindAllStringIndex(line, -1)
+}
+
+// literalMatcher - поиск фиксированных строк (-F)
+type literalMatcher struct {
+	patterns   []string
+	matchEmpty bool // пустой паттерн совпадает с любой строкой
+}
+
+// Функция создает matcher для фиксированных строк
+func newLiteralMatcher(patterns []string) literalMatcher {
+	var m literalMatcher
+	for _, p := range patterns {
+		if p == "" {
+			m.matchEmpty = true
+		} else {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+	return m
+}
+
+func (m literalMatcher) match(line string) bool {
+	if m.matchEmpty {
+		return true
+	}
+	for _, p := range m.patterns {
+		if strings.Contains(line, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m literalMatcher) find(line string) [][]int {
+	var spans [][]int
+	for pos := 0; pos < len(line); {
+		// самое левое совпадение, при равенстве - самое длинное
+		start, end := -1, -1
+		for _, p := range m.patterns {
+			i := strings.Index(line[pos:], p)
+			if i < 0 {
+				continue
+			}
+			i += pos
+			if start < 0 || i < start || (i == start && i+len(p) > end) {
+				start, end = i, i+len(p)
+			}
+		}
+		if start < 0 {
+			break
+		}
+		spans = append(spans, []int{start, end})
+		pos = end
+	}
+	return spans
+}
+
+// lineMatcher - сравнение всей строки с фиксированными строками (-F -x)
+type lineMatcher map[string]struct{}
+
+// Функция создает matcher для сравнения строки целиком
+func newLineMatcher(patterns []string) lineMatcher {
+	m := make(lineMatcher, len(patterns))
+	for _, p := range patterns {
+		m[p] = struct{}{}
+	}
+	return m
+}
+
+func (m lineMatcher) match(line string) bool {
+	_, ok := m[line]
+	return ok
+}
+
+func (m lineMatcher) find(line string) [][]int {
+	if m.match(line) {
+		return [][]int{{0, len(line)}}
+	}
+	return nil
+}
+
+// wordMatcher оставляет только совпадения, которые являются целыми словами (-w):
+// перед совпадением начало строки или не словесный символ, после - конец строки или не словесный символ.
+// Если самое длинное совпадение не является словом, как в GNU grep пробуются более короткие с того же начала
+type wordMatcher struct {
+	inner matcher
+	whole matcher // совпадение паттерна со всей строкой: проверка более коротких совпадений
+}
+
+func (m wordMatcher) match(line string) bool {
+	return len(m.find(line)) > 0
+}
+
+func (m wordMatcher) find(line string) [][]int {
+	var spans [][]int
+	for _, span := range m.inner.find(line) {
+		start := span[0]
+		if !wordStart(line, start) {
+			continue
+		}
+		// от самого длинного совпадения к более коротким, только на границах слов
+		for end := span[1]; ; end = prevRuneStart(line, end) {
+			if wordEnd(line, end) && (end == span[1] || m.whole.match(line[start:end])) {
+				spans = append(spans, []int{start, end})
+				break
+			}
+			if end == start {
+				break
+			}
+		}
+	}
+	return spans
+}
+
+// Функция сообщает, начинается ли в позиции i слово: перед ней начало строки или не словесный символ
+func wordStart(line string, i int) bool {
+	before, _ := utf8.DecodeLastRuneInString(line[:i])
+	return i == 0 || !isWordRune(before)
+}
+
+// Функция сообщает, заканчивается ли в позиции i слово: после нее конец строки или не словесный символ
+func wordEnd(line string, i int) bool {
+	after, _ := utf8.DecodeRuneInString(line[i:])
+	return i == len(line) || !isWordRune(after)
+}
+
+// Функция возвращает начало символа, который заканчивается в позиции i
+func prevRuneStart(line string, i int) int {
+	_, size := utf8.DecodeLastRuneInString(line[:i])
+	return i - size
+}
+
+// Функция сообщает, является ли символ частью слова: буква, цифра или подчеркивание
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Функция читает паттерны из файла (-f), по одному на строку
+func readPatternFile(name string) ([]string, error) {
+	var file *os.File
+	if name == "-" {
+		file = os.Stdin
+	} else {
+		var err error
+		file, err = os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("could not open pattern file %s: %w", name, err)
+		}
+		defer file.Close()
+	}
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read pattern file %s: %w", name, err)
+	}
+	return patterns, nil
+}
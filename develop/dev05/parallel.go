@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+)
+
+// fileResult - результат поиска в одном файле: готовый вывод и число совпадений
+type fileResult struct {
+	output  []byte
+	n       int
+	grouped bool      // в выводе есть группы строк, перед первой может понадобиться разделитель
+	stats   jsonStats // статистика для --json
+	err     error
+}
+
+// fileJob - файл в очереди на поиск. Результат приходит в done
+type fileJob struct {
+	path string
+	done chan fileResult
+}
+
+// Функция ищет совпадения в файлах на пуле из options.jobs горутин.
+// Вывод каждого файла накапливается в буфере и печатается целиком в порядке обхода,
+// поэтому результат совпадает с последовательным поиском.
+// С -q функция возвращается сразу после первого совпадения, не дожидаясь воркеров:
+// они могут пережить вызов, пока не дочитают строку или не закроется заблокированный вход
+func searchParallel(files []string, s *searcher) (int, error) {
+	// с -q после совпадения воркеров не ждем: файл может читаться долго или вообще не дочитаться (FIFO)
+	var wg sync.WaitGroup
+	found := false
+	defer func() {
+		if !found {
+			wg.Wait()
+		}
+	}()
+	stop := make(chan struct{}) // закрывается, когда результаты больше не нужны
+	defer close(stop)
+
+	order := make(chan fileJob, 4*s.options.jobs) // задания в порядке вывода
+	paths := make(chan fileJob)                   // задания для воркеров
+
+	// с -q порядок не важен: достаточно узнать о первом совпадении в любом файле
+	matched := make(chan struct{})
+	var matchedOnce sync.Once
+
+	// обход файлов
+	go func() {
+		defer close(order)
+		defer close(paths)
+
+		// ошибки обхода отдаем в общем порядке вывода
+		w := newWalker(s.options, func(err error) {
+			job := fileJob{done: make(chan fileResult, 1)}
+			job.done <- fileResult{err: err}
+			select {
+			case order <- job:
+			case <-stop:
+			}
+		})
+		for _, operand := range files {
+			err := w.walk(operand, func(path string) error {
+				job := fileJob{path: path, done: make(chan fileResult, 1)}
+				select {
+				case order <- job:
+				case <-stop:
+					return errStopWalk
+				}
+				select {
+				case paths <- job:
+				case <-stop:
+					return errStopWalk
+				}
+				return nil
+			})
+			if errors.Is(err, errStopWalk) {
+				return
+			}
+		}
+	}()
+
+	// воркеры копируют searcher с шаблона: s.out меняется в цикле вывода
+	tmpl := s.withOutput(io.Discard)
+	tmpl.stop = stop
+	for i := 0; i < s.options.jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range paths {
+				var buf bytes.Buffer
+				fs := tmpl.withOutput(&buf)
+				n, err := fs.searchFile(job.path)
+				if flushErr := fs.out.flush(); err == nil {
+					err = flushErr
+				}
+				if n > 0 && s.options.quiet {
+					matchedOnce.Do(func() { close(matched) })
+				}
+				job.done <- fileResult{output: buf.Bytes(), n: n, grouped: fs.out.printedGroup, stats: fs.out.total, err: err}
+			}
+		}()
+	}
+
+	// вывод в порядке обхода
+	total := 0
+	for job := range order {
+		var r fileResult
+		select {
+		case r = <-job.done:
+		case <-matched:
+			found = true
+			return total + 1, nil
+		}
+
+		total += r.n
+		s.out.total.add(r.stats)
+		if r.grouped {
+			// разделитель между группами разных файлов печатаем здесь: воркер не знает, что было до него
+			if err := s.out.startGroup(); err != nil {
+				return total, err
+			}
+		}
+		if len(r.output) > 0 {
+			if err := s.out.write(r.output); err != nil {
+				return total, err
+			}
+		}
+		if isFileError(r.err) {
+			s.reportError(r.err)
+		} else if r.err != nil {
+			return total, r.err
+		}
+		if s.options.quiet && total > 0 {
+			found = true
+			return total, nil
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,552 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readBufferSize - размер буфера чтения. Строки длиннее буфера читаются целиком,
+// в отличие от bufio.Scanner с его ограничением в 64KB
+const readBufferSize = 64 * 1024
+
+// stdinName - имя стандартного ввода в выводе, как в GNU grep
+const stdinName = "(standard input)"
+
+// bufferedLine - строка входного потока вместе с ее номером
+type bufferedLine struct {
+	num    int
+	text   string
+	offset int // смещение начала строки от начала входа в байтах (-b)
+}
+
+// ring - кольцевой буфер последних строк для контекста -B
+type ring struct {
+	lines []bufferedLine
+	start int
+	size  int
+}
+
+// Функция создает кольцевой буфер на n строк
+func newRing(n int) *ring {
+	return &ring{lines: make([]bufferedLine, n)}
+}
+
+// push добавляет строку, вытесняя самую старую, если буфер заполнен
+func (r *ring) push(l bufferedLine) {
+	if len(r.lines) == 0 {
+		return
+	}
+	if r.size < len(r.lines) {
+		r.lines[(r.start+r.size)%len(r.lines)] = l
+		r.size++
+		return
+	}
+	r.lines[r.start] = l
+	r.start = (r.start + 1) % len(r.lines)
+}
+
+// drain передает строки в fn от старой к новой и очищает буфер
+func (r *ring) drain(fn func(bufferedLine)) {
+	for i := 0; i < r.size; i++ {
+		fn(r.lines[(r.start+i)%len(r.lines)])
+	}
+	r.start, r.size = 0, 0
+}
+
+// printer - буферизованный вывод результатов
+type printer struct {
+	w            *bufio.Writer
+	lineBuffered bool // сбрасывать буфер после каждой строки
+	lineNum      bool // -n
+	withFilename bool // печатать имя файла перед строкой
+	byteOffset   bool // -b
+	column       bool // --column
+	invert       bool // -v, от него зависят цвета строк
+
+	colors *colorScheme // без цвета все поля пустые
+
+	separator    string // разделитель групп контекста
+	useSeparator bool   // печатать разделитель между группами
+	printedGroup bool   // уже напечатана хотя бы одна группа
+
+	json   bool      // --json
+	began  bool      // для текущего файла напечатано begin
+	binary bool      // текущий файл оказался двоичным
+	file   jsonStats // статистика текущего файла
+	total  jsonStats // статистика всех просмотренных файлов
+}
+
+// Разделители после имени файла и номера строки, как в GNU grep
+const (
+	matchSep   = ':' // строка с совпадением
+	contextSep = '-' // строка контекста
+)
+
+// Функция создает printer. При lineBuffered каждая строка сразу уходит в w
+func newPrinter(w io.Writer, lineBuffered bool, options grepOptions) *printer {
+	p := &printer{
+		w:            bufio.NewWriter(w),
+		lineBuffered: lineBuffered,
+		lineNum:      options.lineNum,
+		byteOffset:   options.byteOffset,
+		column:       options.column,
+		invert:       options.invert,
+		colors:       options.colors,
+		json:         options.json,
+	}
+	if p.colors == nil {
+		p.colors = &colorScheme{}
+	}
+	p.separator, p.useSeparator = options.separator()
+	return p
+}
+
+// line печатает строку входного потока. sep отличает совпадение (matchSep) от контекста (contextSep),
+// spans - границы совпадений для подсветки и --column (могут быть nil)
+func (p *printer) line(name string, l bufferedLine, sep byte, spans [][]int) error {
+	if p.json {
+		return p.jsonLine(name, l, sep, spans)
+	}
+
+	selected, context := p.colors.lineColors(p.invert)
+	lineColor, matchColor := selected, p.colors.selectedMatch
+	if sep == contextSep {
+		lineColor, matchColor = context, p.colors.contextMatch
+	}
+
+	column := 0
+	if sep == matchSep && len(spans) > 0 {
+		column = spans[0][0] + 1
+	}
+	p.prefix(name, l.num, column, l.offset, sep)
+
+	pos := 0
+	for _, span := range spans {
+		if span[0] == span[1] {
+			continue
+		}
+		p.paint(lineColor, l.text[pos:span[0]])
+		p.paint(matchColor, l.text[span[0]:span[1]])
+		pos = span[1]
+	}
+	p.paint(lineColor, l.text[pos:])
+	return p.endLine()
+}
+
+// onlyMatch печатает совпавшую часть строки отдельной строкой (-o)
+func (p *printer) onlyMatch(name string, l bufferedLine, span []int) error {
+	p.prefix(name, l.num, span[0]+1, l.offset+span[0], matchSep)
+	p.paint(p.colors.selectedMatch, l.text[span[0]:span[1]])
+	return p.endLine()
+}
+
+// prefix печатает имя файла, номер строки, колонку (0 - не печатать) и смещение в байтах
+func (p *printer) prefix(name string, num, column, offset int, sep byte) {
+	if p.withFilename {
+		p.paint(p.colors.fileName, name)
+		p.paint(p.colors.separator, string(sep))
+	}
+	if p.lineNum {
+		p.paint(p.colors.lineNum, strconv.Itoa(num))
+		p.paint(p.colors.separator, string(sep))
+	}
+	if p.column && column > 0 {
+		p.paint(p.colors.lineNum, strconv.Itoa(column))
+		p.paint(p.colors.separator, string(sep))
+	}
+	if p.byteOffset {
+		p.paint(p.colors.byteOffset, strconv.Itoa(offset))
+		p.paint(p.colors.separator, string(sep))
+	}
+}
+
+// paint печатает текст в цвете sgr. Пустой sgr - без цвета
+func (p *printer) paint(sgr, text string) {
+	if sgr == "" || text == "" {
+		p.w.WriteString(text)
+		return
+	}
+	p.w.WriteString(p.colors.start(sgr))
+	p.w.WriteString(text)
+	p.w.WriteString(p.colors.end())
+}
+
+// Функция сообщает, подсвечиваются ли совпадения
+func (p *printer) highlights() bool {
+	return p.colors.selectedMatch != "" || p.colors.contextMatch != ""
+}
+
+// startGroup начинает новую группу строк и печатает разделитель, если до нее уже была группа
+func (p *printer) startGroup() error {
+	wasPrinted := p.printedGroup
+	p.printedGroup = true
+	if p.useSeparator && wasPrinted {
+		p.paint(p.colors.separator, p.separator)
+		return p.endLine()
+	}
+	return nil
+}
+
+// count печатает число совпавших строк в файле (-c)
+func (p *printer) count(name string, n int) error {
+	if p.withFilename {
+		p.paint(p.colors.fileName, name)
+		p.paint(p.colors.separator, ":")
+	}
+	p.w.WriteString(strconv.Itoa(n))
+	return p.endLine()
+}
+
+// fileName печатает имя файла для -l и -L
+func (p *printer) fileName(name string) error {
+	p.paint(p.colors.fileName, name)
+	return p.endLine()
+}
+
+// binaryMatch сообщает о совпадении в двоичном файле
+func (p *printer) binaryMatch(name string) error {
+	if p.json {
+		p.binary = true
+		return p.beginFile(name)
+	}
+	return p.text("Binary file " + name + " matches")
+}
+
+// write печатает уже отформатированный вывод, например, результат поиска в отдельном файле
+func (p *printer) write(b []byte) error {
+	if _, err := p.w.Write(b); err != nil {
+		return err
+	}
+	if p.lineBuffered {
+		return p.w.Flush()
+	}
+	return nil
+}
+
+// text печатает произвольную строку, например, сообщение о двоичном файле
+func (p *printer) text(s string) error {
+	p.w.WriteString(s)
+	return p.endLine()
+}
+
+// Функция завершает строку вывода и при построчной буферизации сбрасывает буфер
+func (p *printer) endLine() error {
+	if err := p.w.WriteByte('\n'); err != nil {
+		return err
+	}
+	if p.lineBuffered {
+		return p.w.Flush()
+	}
+	return nil
+}
+
+// flush сбрасывает буфер вывода
+func (p *printer) flush() error {
+	return p.w.Flush()
+}
+
+// fileError - ошибка открытия или чтения одного файла. В отличие от ошибок вывода,
+// после нее поиск продолжается с остальными файлами
+type fileError struct {
+	err error
+}
+
+func (e fileError) Error() string {
+	return e.err.Error()
+}
+
+func (e fileError) Unwrap() error {
+	return e.err
+}
+
+// Функция создает fileError с сообщением, как fmt.Errorf
+func fileErrorf(format string, args ...any) error {
+	return fileError{fmt.Errorf(format, args...)}
+}
+
+// Функция сообщает, относится ли ошибка к отдельному файлу
+func isFileError(err error) bool {
+	var fe fileError
+	return errors.As(err, &fe)
+}
+
+// searcher - потоковый поиск. В памяти хранится только текущая строка
+// и кольцевой буфер строк для контекста -B, совпадения печатаются сразу
+type searcher struct {
+	options grepOptions
+	m       matcher
+	out     *printer
+	stdin   io.Reader       // откуда читается операнд "-"
+	errOut  io.Writer       // куда печатаются ошибки файлов
+	failed  bool            // была ошибка файла: код возврата 2
+	stop    <-chan struct{} // закрывается, когда результаты поиска больше не нужны
+}
+
+// Функция создает searcher для списка файлов
+func newSearcher(options grepOptions, m matcher, files []string, w io.Writer, lineBuffered bool) *searcher {
+	out := newPrinter(w, lineBuffered, options)
+	out.withFilename = showFilenames(options, files)
+	return &searcher{options: options, m: m, out: out, stdin: os.Stdin, errOut: os.Stderr}
+}
+
+// Функция решает, печатать ли имена файлов: да с -H или если файлов может быть несколько -
+// несколько операндов или рекурсивный обход директории; нет с -h
+func showFilenames(options grepOptions, files []string) bool {
+	switch {
+	case options.withFilename:
+		return true
+	case options.noFilename:
+		return false
+	case len(files) > 1:
+		return true
+	case !options.isRecursive():
+		return false
+	case len(files) == 0:
+		return true
+	}
+	info, err := os.Stat(files[0])
+	return err == nil && info.IsDir()
+}
+
+// Функция сообщает, нужно ли печатать сами строки, а не только итоги по файлу
+func (o grepOptions) printLines() bool {
+	return !o.count && !o.filesWithMatches && !o.filesWithoutMatch && !o.quiet
+}
+
+// Функция сообщает, достаточно ли первого совпадения, чтобы закончить с файлом
+func (o grepOptions) firstMatchOnly() bool {
+	return o.filesWithMatches || o.filesWithoutMatch || o.quiet
+}
+
+// withOutput возвращает копию searcher, которая пишет в w. Используется для поиска
+// в нескольких файлах одновременно: matcher можно разделять между горутинами
+func (s *searcher) withOutput(w io.Writer) *searcher {
+	out := *s.out
+	out.w = bufio.NewWriter(w)
+	out.lineBuffered = false
+	out.printedGroup = false
+	out.total = jsonStats{}
+	return &searcher{options: s.options, m: s.m, out: &out, stdin: s.stdin, errOut: s.errOut, stop: s.stop}
+}
+
+// Функция сообщает, что результаты поиска больше не нужны и чтение можно прекратить
+func (s *searcher) stopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// reportError печатает ошибку файла (с -s молча) и запоминает, что она была
+func (s *searcher) reportError(err error) {
+	s.failed = true
+	if !s.options.noMessages {
+		fmt.Fprintln(s.errOut, "Error:", err)
+	}
+}
+
+// searchFile ищет совпадения в файле ("-" - стандартный ввод) и печатает итоги по нему.
+// С -z сжатые файлы распаковываются
+func (s *searcher) searchFile(fileName string) (int, error) {
+	r := s.stdin
+	name := stdinName
+	if fileName != "-" {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return 0, fileErrorf("could not open file %s: %w", fileName, err)
+		}
+		defer file.Close()
+		r, name = file, fileName
+	}
+
+	// сжатый файл распаковываем на лету, а имя печатаем как есть
+	if s.options.searchZip {
+		var err error
+		if r, err = decompress(r, name); err != nil {
+			return 0, fileErrorf("could not read file %s: %w", name, err)
+		}
+	}
+
+	n, err := s.search(r, name)
+	if err != nil {
+		return n, err
+	}
+	return n, s.report(name, n)
+}
+
+// Функция печатает итоги по файлу для -l, -L и -c
+func (s *searcher) report(name string, n int) error {
+	switch {
+	case s.options.quiet:
+	case s.options.filesWithMatches:
+		if n > 0 {
+			return s.out.fileName(name)
+		}
+	case s.options.filesWithoutMatch:
+		if n == 0 {
+			return s.out.fileName(name)
+		}
+	case s.options.count:
+		return s.out.count(name, n)
+	}
+	return nil
+}
+
+// search ищет совпадения в r и возвращает число совпавших строк. С --json в конце печатается end
+func (s *searcher) search(r io.Reader, name string) (int, error) {
+	start := time.Now()
+	n, size, err := s.scan(r, name)
+	if err == nil && s.options.json {
+		err = s.out.endFile(name, n, size, time.Since(start))
+	}
+	return n, err
+}
+
+// scan ищет совпадения в r и возвращает число совпавших строк и число прочитанных байт.
+// Для -l, -L и -q чтение прекращается на первом совпадении, для -m NUM - после NUM-го
+// совпадения и следующих за ним строк контекста.
+// Файл считается двоичным, если в нем встречается нулевой байт: для двоичного файла
+// вместо строк печатается одно сообщение о совпадении (или файл пропускается с -I)
+func (s *searcher) scan(r io.Reader, name string) (int, int, error) {
+	br := bufio.NewReaderSize(r, readBufferSize)
+
+	detectBinary := s.options.binaryFiles != binaryText
+	binary := false
+	if detectBinary {
+		// смотрим только то, что пришло первым чтением, чтобы не ждать заполнения буфера
+		br.Peek(1)
+		head, _ := br.Peek(br.Buffered())
+		binary = bytes.IndexByte(head, 0) >= 0
+	}
+	if binary && s.options.binaryFiles == binaryWithoutMatch {
+		return 0, 0, nil
+	}
+	before := newRing(s.options.beforeContext())
+	after := s.options.afterContext()
+
+	matches := 0
+	afterLeft := 0        // сколько строк контекста -A осталось напечатать
+	limitReached := false // набрано -m совпадений, дочитываем только контекст
+	lastPrinted := 0      // номер последней напечатанной строки, 0 - еще ничего не напечатано
+	var err error
+	emitAs := func(sep byte) func(bufferedLine) {
+		return func(l bufferedLine) {
+			// строка не продолжает предыдущую - значит, начинается новая группа
+			if err == nil && (lastPrinted == 0 || l.num > lastPrinted+1) {
+				err = s.out.startGroup()
+			}
+			if err == nil {
+				err = s.printLine(name, l, sep)
+			}
+			lastPrinted = l.num
+		}
+	}
+	emitMatch, emitContext := emitAs(matchSep), emitAs(contextSep)
+
+	offset := 0
+	for num := 1; err == nil; num++ {
+		if (limitReached && afterLeft == 0) || s.stopped() {
+			break
+		}
+		text, readErr := readLine(br)
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return matches, offset, fileErrorf("could not read file %s: %w", name, readErr)
+		}
+
+		if detectBinary && !binary && strings.IndexByte(text, 0) >= 0 {
+			binary = true
+			if s.options.binaryFiles == binaryWithoutMatch {
+				break
+			}
+		}
+
+		l := bufferedLine{num: num, text: text, offset: offset}
+		offset += len(text) + 1
+		if limitReached {
+			emitContext(l)
+			afterLeft--
+			continue
+		}
+
+		if s.m.match(text) != s.options.invert {
+			matches++
+			if s.options.firstMatchOnly() {
+				break
+			}
+			limitReached = s.options.maxCount > 0 && matches >= s.options.maxCount
+			if !s.options.printLines() {
+				if limitReached {
+					break
+				}
+				continue
+			}
+			if binary {
+				err = s.out.binaryMatch(name)
+				break
+			}
+			before.drain(emitContext)
+			emitMatch(l)
+			afterLeft = after
+		} else if afterLeft > 0 {
+			emitContext(l)
+			afterLeft--
+		} else {
+			before.push(l)
+		}
+	}
+	return matches, offset, err
+}
+
+// printLine печатает строку целиком или, с -o, каждое совпадение в ней отдельно
+func (s *searcher) printLine(name string, l bufferedLine, sep byte) error {
+	// совпадения есть в выбранных строках без -v и в строках контекста с -v
+	var spans [][]int
+	if (sep == matchSep) != s.options.invert &&
+		(s.options.onlyMatching || s.options.column || s.options.json || s.out.highlights()) {
+		spans = s.m.find(l.text)
+	}
+
+	if !s.options.onlyMatching || s.options.json {
+		return s.out.line(name, l, sep, spans)
+	}
+	for _, span := range spans {
+		if span[0] == span[1] {
+			continue
+		}
+		if err := s.out.onlyMatch(name, l, span); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Функция читает строку любой длины без завершающего перевода строки.
+// Последняя строка без перевода строки тоже возвращается
+func readLine(br *bufio.Reader) (string, error) {
+	raw, err := br.ReadString('\n')
+	if err == io.EOF && raw != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(raw, "\n"), nil
+}
+
+// Функция сообщает, выводит ли файл в терминал
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
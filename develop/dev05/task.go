@@ -1,11 +1,14 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"time"
 )
 
 /*
@@ -36,9 +39,74 @@ type grepOptions struct {
 	fixed		bool	// -F
 	lineNum		bool	// -n
 	pattern		string	// паттерн
+
+	basic		bool	// -G
+	extended	bool	// -E
+	perl		bool	// -P
+	wordRegexp	bool	// -w
+	lineRegexp	bool	// -x
+	patterns	[]string	// -e и -f
+	patternsSet	bool	// паттерны заданы через -e или -f
+	lineBuffered	bool	// --line-buffered
+	withFilename	bool	// -H
+	noFilename	bool	// -h
+	filesWithMatches	bool	// -l
+	filesWithoutMatch	bool	// -L
+	recursive	bool	// -r
+	dereferenceRecursive	bool	// -R
+	include		stringList	// --include
+	exclude		stringList	// --exclude
+	excludeDir	stringList	// --exclude-dir
+	binaryFiles	string	// --binary-files: binary, text или without-match
+	gitignore	bool	// --gitignore
+	jobs		int	// -j
+	maxCount	int	// -m, 0 - без ограничения
+	quiet		bool	// -q
+	groupSeparator	string	// --group-separator
+	groupSeparatorSet	bool	// --group-separator задан явно, иначе "--"
+	noGroupSeparator	bool	// --no-group-separator
+	onlyMatching	bool	// -o
+	byteOffset	bool	// -b
+	column		bool	// --column
+	color		colorFlag	// --color: auto, always или never
+	colors		*colorScheme	// цвета вывода, nil - без цвета
+	json		bool	// --json
+	searchZip	bool	// -z: искать в сжатых файлах gzip, bzip2 и zlib
+	noMessages	bool	// -s
+	smartCase	bool	// --smart-case
 }
 
-var options grepOptions
+// stringList - флаг, который можно указать несколько раз
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+var (
+	options      grepOptions
+	patternArgs  stringList // -e
+	patternFiles stringList // -f
+)
+
+// Коды возврата, как в POSIX grep
+const (
+	exitMatch   = 0 // есть выбранные строки
+	exitNoMatch = 1 // выбранных строк нет
+	exitError   = 2 // ошибка
+)
+
+// Значения --binary-files
+const (
+	binaryDefault      = "binary"        // сообщать о совпадении в двоичном файле без вывода строк
+	binaryText         = "text"          // считать двоичные файлы текстом
+	binaryWithoutMatch = "without-match" // считать, что в двоичных файлах совпадений нет
+)
 
 func init() {
 	flag.IntVar(&options.after, "A", 0, "Print +N lines after match")
@@ -47,153 +115,302 @@ func init() {
 	flag.BoolVar(&options.count, "c", false, "Print count of matching lines")
 	flag.BoolVar(&options.ignoreCase, "i", false, "Ignore case")
 	flag.BoolVar(&options.invert, "v", false, "Invert match")
-	flag.BoolVar(&options.fixed, "F", false, "Exact match, not a pattern")
+	flag.BoolVar(&options.fixed, "F", false, "Interpret patterns as fixed strings")
 	flag.BoolVar(&options.lineNum, "n", false, "Print line numbers")
+	flag.BoolVar(&options.basic, "G", false, "Interpret patterns as basic regular expressions (default)")
+	flag.BoolVar(&options.extended, "E", false, "Interpret patterns as extended regular expressions")
+	flag.BoolVar(&options.perl, "P", false, "Interpret patterns as Perl-like regular expressions")
+	flag.BoolVar(&options.wordRegexp, "w", false, "Match only whole words")
+	flag.BoolVar(&options.lineRegexp, "x", false, "Match only whole lines")
+	flag.Var(&patternArgs, "e", "Use `PATTERN` for matching (may be repeated)")
+	flag.Var(&patternFiles, "f", "Take patterns from `FILE`, one per line (may be repeated)")
+	flag.BoolVar(&options.lineBuffered, "line-buffered", false, "Flush output on every line")
+	flag.BoolVar(&options.withFilename, "H", false, "Print the file name for each match")
+	flag.BoolVar(&options.noFilename, "h", false, "Suppress the file name prefix on output")
+	flag.BoolVar(&options.filesWithMatches, "l", false, "Print only names of files with matches")
+	flag.BoolVar(&options.filesWithoutMatch, "L", false, "Print only names of files without matches")
+	flag.BoolVar(&options.recursive, "r", false, "Search directories recursively")
+	flag.BoolVar(&options.dereferenceRecursive, "R", false, "Search directories recursively, following all symlinks")
+	flag.Var(&options.include, "include", "Search only files whose base name matches `GLOB` (may be repeated)")
+	flag.Var(&options.exclude, "exclude", "Skip files whose base name matches `GLOB` (may be repeated)")
+	flag.Var(&options.excludeDir, "exclude-dir", "Skip directories whose base name matches `GLOB` (may be repeated)")
+	flag.StringVar(&options.binaryFiles, "binary-files", binaryDefault, "How to treat binary files: `TYPE` is binary, text or without-match")
+	flag.BoolFunc("a", "Process binary files as text (--binary-files=text)", func(string) error {
+		options.binaryFiles = binaryText
+		return nil
+	})
+	flag.BoolFunc("I", "Skip binary files (--binary-files=without-match)", func(string) error {
+		options.binaryFiles = binaryWithoutMatch
+		return nil
+	})
+	flag.BoolVar(&options.gitignore, "gitignore", false, "Skip files ignored by .gitignore when searching recursively")
+	flag.IntVar(&options.jobs, "j", 1, "Search up to `N` files concurrently")
+	flag.IntVar(&options.maxCount, "m", 0, "Stop reading a file after `NUM` matching lines (0 means no limit)")
+	flag.BoolVar(&options.quiet, "q", false, "Quiet; exit immediately with zero status if any match is found")
+	flag.Func("group-separator", "Print `SEP` between groups of context lines (default \"--\")", func(value string) error {
+		options.groupSeparator = value
+		options.groupSeparatorSet = true
+		return nil
+	})
+	flag.BoolVar(&options.noGroupSeparator, "no-group-separator", false, "Do not print a separator between groups of context lines")
+	flag.BoolVar(&options.onlyMatching, "o", false, "Print only the matched parts of lines, each on its own line")
+	flag.BoolVar(&options.byteOffset, "b", false, "Print the byte offset of each line (or match with -o)")
+	flag.BoolVar(&options.column, "column", false, "Print the column of the first match on each line")
+	flag.Var(&options.color, "color", "Highlight matches: `WHEN` is auto, always or never; colors are taken from GREP_COLORS")
+	flag.Var(&options.color, "colour", "Same as --color")
+	flag.BoolVar(&options.json, "json", false, "Print results as JSON Lines: begin, match, context, end and summary events")
+	flag.BoolVar(&options.searchZip, "z", false, "Search in gzip, bzip2 and zlib compressed files (detected by extension or contents)")
+	flag.BoolVar(&options.searchZip, "search-zip", false, "Same as -z")
+	flag.BoolVar(&options.smartCase, "smart-case", false, "Ignore case if the patterns contain no uppercase letters")
+	flag.BoolVar(&options.noMessages, "s", false, "Suppress error messages about nonexistent or unreadable files")
 }
 
-func parseFlags() {
+// Функция разбирает флаги и возвращает список файлов
+func parseFlags() []string {
 	flag.Parse()
+	args := flag.Args()
+	options.resolveContext(setFlags())
 
-	if flag.NArg() < 1 {
-		fmt.Fprintln(os.Stderr, "Error: pattern not provided")
-		os.Exit(1)
+	if err := options.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitError)
+	}
+	if options.color.enabled(isTerminal(os.Stdout)) {
+		options.colors = parseGrepColors(os.Getenv("GREP_COLORS"))
 	}
-	options.pattern = flag.Arg(0)
-}
-
-// Функция открытия файла и чтения из него или из stdin
-func readInput() ([]string, error) {
-	var lines []string
 
-	// Если указаны файлы
-	if flag.NArg() > 1 {
-		// открываем файл
-		for _, fileName := range flag.Args()[1:] {
-			file, err := os.Open(fileName)
+	// Паттерны из -e и -f, иначе паттерн - первый аргумент
+	if len(patternArgs) > 0 || len(patternFiles) > 0 {
+		options.patternsSet = true
+		options.patterns = append(options.patterns, patternArgs...)
+		for _, name := range patternFiles {
+			patterns, err := readPatternFile(name)
 			if err != nil {
-				return nil, fmt.Errorf("could not open file %s: %w", fileName, err)
-			}
-			defer file.Close()
-			
-			// читаем из файла
-			scanner := bufio.NewScanner(file)
-			for scanner.Scan() {
-				lines = append(lines, scanner.Text())
-			}
-			if err := scanner.Err(); err != nil {
-				return nil, err
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(exitError)
 			}
+			options.patterns = append(options.patterns, patterns...)
 		}
-		return lines, nil
+		return args
 	}
 
-	// Если файл не указан, читаем из stdin
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Error: pattern not provided")
+		os.Exit(exitError)
+	}
+	options.pattern = args[0]
+	return args[1:]
+}
+
+// Функция возвращает имена флагов, заданных в командной строке
+func setFlags() map[string]bool {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// Функция раскладывает -C по -A и -B: явно заданные -A и -B важнее -C, а не складываются с ним
+func (o *grepOptions) resolveContext(set map[string]bool) {
+	if !set["C"] {
+		return
+	}
+	if !set["A"] {
+		o.after = o.context
+	}
+	if !set["B"] {
+		o.before = o.context
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	o.context = 0
+}
+
+// Функция возвращает число строк контекста после совпадения.
+// Если -A не задан, используется -C. С -o контекст не печатается
+func (o grepOptions) afterContext() int {
+	if o.onlyMatching {
+		return 0
 	}
-	return lines, nil
+	if o.after > 0 {
+		return o.after
+	}
+	return o.context
 }
 
-// Функция применяет фильтры к строкам
-func grep(lines []string, options grepOptions) ([]string, int) {
-	var matches []string	// строки для вывода
-	totalMatches := 0	// число совпадений
-	// Мама для отслеживания добавленных строк
-	seen := map[int]struct{}{}
+// Функция возвращает число строк контекста до совпадения.
+// Если -B не задан, используется -C. С -o контекст не печатается
+func (o grepOptions) beforeContext() int {
+	if o.onlyMatching {
+		return 0
+	}
+	if o.before > 0 {
+		return o.before
+	}
+	return o.context
+}
 
-	// Нормализуем паттерн, если включен -i
-	pattern := options.pattern
-	if options.ignoreCase {
-		pattern = strings.ToLower(pattern)	// приводим к нижнему регистру
+// Функция возвращает разделитель групп контекста и false, если разделитель не печатается
+func (o grepOptions) separator() (string, bool) {
+	if o.noGroupSeparator || o.json || (o.afterContext() == 0 && o.beforeContext() == 0) {
+		return "", false
 	}
+	if o.groupSeparatorSet {
+		return o.groupSeparator, true
+	}
+	return "--", true
+}
 
-	// Проходим по каждой строке
-	for i, line := range lines {
-		// Флаг -i 
-		if options.ignoreCase {
-			line = strings.ToLower(line)	// приводим строку к нижнему регистру
+// Функция проверяет значения флагов
+func (o grepOptions) validate() error {
+	if o.after < 0 || o.before < 0 || o.context < 0 {
+		return errors.New("invalid context length argument")
+	}
+	switch o.binaryFiles {
+	case "", binaryDefault, binaryText, binaryWithoutMatch:
+	default:
+		return fmt.Errorf("invalid --binary-files value %q", o.binaryFiles)
+	}
+	if o.jobs < 1 {
+		return fmt.Errorf("invalid number of jobs %d", o.jobs)
+	}
+	if o.maxCount < 0 {
+		return fmt.Errorf("invalid max count %d", o.maxCount)
+	}
+	if o.json && (o.count || o.filesWithMatches || o.filesWithoutMatch || o.quiet) {
+		return errors.New("--json cannot be combined with -c, -l, -L or -q")
+	}
+	for _, globs := range []stringList{o.include, o.exclude, o.excludeDir} {
+		if err := validateGlobs(globs); err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+// errStopWalk прерывает обход файлов, когда дальше искать не нужно
+var errStopWalk = errors.New("stop walking")
 
-		var match bool	// было ли совпадение
-		// Флаг -F
-		if options.fixed {
-			match = line == pattern	// если строка полностью совпадает с паттерном
+// Функция ищет совпадения в файлах или, если файлы не указаны, в stdin
+// (с -r - в текущей директории). Возвращает общее число совпадений.
+// С -j N файлы просматриваются параллельно, вывод остается в порядке файлов.
+// С --json в конце печатается summary
+func searchFiles(files []string, s *searcher) (int, error) {
+	start := time.Now()
+	if len(files) == 0 {
+		if s.options.isRecursive() {
+			files = []string{"."}
 		} else {
-			match = strings.Contains(line, pattern)	// содержится ли паттерн в строке
+			files = []string{"-"}
 		}
+	}
 
-		// Флаг -v
-		if options.invert {
-			match = !match
-		}
+	var total int
+	var err error
+	if s.options.jobs > 1 {
+		total, err = searchParallel(files, s)
+	} else {
+		total, err = searchSequential(files, s)
+	}
+	if err == nil && s.options.json {
+		err = s.out.summary(time.Since(start))
+	}
+	return total, err
+}
 
-		// Если было совпадение
-		if match {
-			totalMatches++
-
-			// Определяем диапазон строк, которые нужно добавить в результат
-			start := max(0, i-options.before-options.context)
-			end := min(len(lines)-1, i+options.after+options.context)
-
-			// Добавляем строки перед, после или с контекстом
-			for j := start; j <= end; j++ {
-				if _, exists := seen[j]; !exists { // чтобы не добавлять одинаковые
-					// Флаг -n
-					if options.lineNum {
-						matches = append(matches, fmt.Sprintf("%d:%s", j+1, lines[j]))
-					} else {
-						matches = append(matches, lines[j])
-					}
-					// Добавляем строку в множество, чтобы избежать дублирования
-					seen[j] = struct{}{}
-				}
+// Функция ищет совпадения в файлах по очереди
+func searchSequential(files []string, s *searcher) (int, error) {
+	w := newWalker(s.options, s.reportError)
+	total := 0
+	for _, operand := range files {
+		err := w.walk(operand, func(path string) error {
+			n, err := s.searchFile(path)
+			total += n
+			if isFileError(err) {
+				// файл не открылся или не прочитался - сообщаем и идем дальше
+				s.reportError(err)
+				return nil
 			}
+			if err == nil && s.options.quiet && total > 0 {
+				return errStopWalk
+			}
+			return err
+		})
+		if errors.Is(err, errStopWalk) {
+			break
+		}
+		if err != nil {
+			return total, err
 		}
 	}
-	// Возвращаем найденные строки и общее количество совпадений
-	return matches, totalMatches
+	return total, nil
 }
 
-// Функция возвращает максимальное значение
-func max(a, b int) int {
-	if a > b {
-		return a
+// Функция применяет фильтры к строкам и возвращает строки для вывода и число совпадений
+func grep(lines []string, options grepOptions) ([]string, int, error) {
+	// Собираем паттерны с учетом -F/-E/-P, -i, -w, -x
+	m, err := newMatcher(options)
+	if err != nil {
+		return nil, 0, err
 	}
-	return b
-}
 
-// Функция возвращает минимальное значение
-func min(a, b int) int {
-	if a < b {
-		return a
+	var buf bytes.Buffer
+	s := newSearcher(options, m, nil, &buf, false)
+	var input strings.Builder
+	for _, line := range lines {
+		input.WriteString(line)
+		input.WriteByte('\n')
 	}
-	return b
-}
-
+	totalMatches, err := s.search(strings.NewReader(input.String()), stdinName)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.out.flush()
 
-func main() {
-	parseFlags()
+	// Разбиваем вывод обратно на строки
+	var matches []string
+	if buf.Len() > 0 {
+		matches = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	}
+	return matches, totalMatches, nil
+}
 
-	// Читаем входные данные
-	lines, err := readInput()
+// Функция выполняет поиск и возвращает код возврата: 0 - есть совпадения, 1 - нет, 2 - ошибка.
+// Ошибки файлов не прерывают поиск, но дают код 2, если только с -q уже не нашлось совпадение
+func run(options grepOptions, files []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	m, err := newMatcher(options)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error reading input:", err)
-		os.Exit(1)
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitError
 	}
 
-	// Обрабатываем флаги
-	matches, totalMatches := grep(lines, options)
+	// Печатаем совпадения по мере чтения, в терминал - построчно
+	lineBuffered := options.lineBuffered
+	if f, ok := stdout.(*os.File); ok && isTerminal(f) {
+		lineBuffered = true
+	}
+	s := newSearcher(options, m, files, stdout, lineBuffered)
+	s.stdin = stdin
+	s.errOut = stderr
 
-	// Выводим результаты
-	if options.count {
-		fmt.Println(totalMatches)
-	} else {
-		for _, match := range matches {
-			fmt.Println(match)
-		}
+	total, err := searchFiles(files, s)
+	if flushErr := s.out.flush(); err == nil {
+		err = flushErr
 	}
+	switch {
+	case err != nil:
+		fmt.Fprintln(stderr, "Error writing output:", err)
+		return exitError
+	case total > 0 && options.quiet:
+		return exitMatch
+	case s.failed:
+		return exitError
+	case total > 0:
+		return exitMatch
+	}
+	return exitNoMatch
+}
+
+func main() {
+	files := parseFlags()
+	os.Exit(run(options, files, os.Stdin, os.Stdout, os.Stderr))
 }
@@ -1,8 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Тест для флага -A (печатает строки после совпадения)
@@ -20,7 +32,10 @@ func TestGrepWithAfterFlag(t *testing.T) {
         "End of the test file.",
     }
 
-    matches, totalMatches := grep(lines, options)
+    matches, totalMatches, err := grep(lines, options)
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
 
     if totalMatches != 1 {
         t.Errorf("Expected 1 match, got %d", totalMatches)
@@ -54,7 +69,10 @@ func TestGrepWithBeforeFlag(t *testing.T) {
         "End of the test file.",
     }
 
-    matches, totalMatches := grep(lines, options)
+    matches, totalMatches, err := grep(lines, options)
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
 
     if totalMatches != 1 {
         t.Errorf("Expected 1 match, got %d", totalMatches)
@@ -87,7 +105,10 @@ func TestGrepWithContextFlag(t *testing.T) {
         "End of the test file.",
     }
 
-    matches, totalMatches := grep(lines, options)
+    matches, totalMatches, err := grep(lines, options)
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
 
     if totalMatches != 1 {
         t.Errorf("Expected 1 match, got %d", totalMatches)
@@ -121,7 +142,10 @@ func TestGrepWithIgnoreCaseFlag(t *testing.T) {
         "End of the test file.",
     }
 
-    matches, totalMatches := grep(lines, options)
+    matches, totalMatches, err := grep(lines, options)
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
 
     if totalMatches != 4 {
         t.Errorf("Expected 4 matches, got %d", totalMatches)
@@ -155,7 +179,10 @@ func TestGrepWithCountFlag(t *testing.T) {
         "Case sensitivity is also something we want to test.",
     }
 
-    _, totalMatches := grep(lines, options)
+    _, totalMatches, err := grep(lines, options)
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
 
     if totalMatches != 3 {
         t.Errorf("Expected 3 matches, got %d", totalMatches)
@@ -176,7 +203,10 @@ func TestGrepWithInvertFlag(t *testing.T) {
         "End of the test file.",
     }
 
-    matches, totalMatches := grep(lines, options)
+    matches, totalMatches, err := grep(lines, options)
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
 
     if totalMatches != 1 {
         t.Errorf("Expected 1 matches, got %d", totalMatches)
@@ -192,3 +222,1444 @@ func TestGrepWithInvertFlag(t *testing.T) {
         }
     }
 }
+
+// Тест для режимов паттернов -G/-E/-P/-F и флагов -w, -x, -e
+func TestGrepPatterns(t *testing.T) {
+	lines := []string{
+		"foo bar",
+		"aaab",
+		"a+b (x)",
+		"abcabc",
+		"id=42",
+		"foobar",
+		"a.b",
+		"Straße",
+		"a bc",
+	}
+
+	tests := []struct {
+		name     string
+		options  grepOptions
+		expected []string
+	}{
+		{"BRE plus is literal", grepOptions{pattern: "a+b"}, []string{"a+b (x)"}},
+		{"BRE escaped plus", grepOptions{pattern: `a\+b`}, []string{"aaab", "abcabc"}},
+		{"BRE parens are literal", grepOptions{pattern: "(x)"}, []string{"a+b (x)"}},
+		{"BRE group and interval", grepOptions{pattern: `^\(abc\)\{2\}$`}, []string{"abcabc"}},
+		{"BRE alternation", grepOptions{pattern: `42\|bar$`}, []string{"foo bar", "id=42", "foobar"}},
+		{"BRE star at start is literal", grepOptions{pattern: "*"}, nil},
+		{"BRE bracket with backslash", grepOptions{pattern: `[\]`}, nil},
+		{"BRE character class", grepOptions{pattern: "^[[:alpha:]]*$"}, []string{"aaab", "abcabc", "foobar", "Straße"}},
+		{"ERE", grepOptions{pattern: "a+b$|^id=[0-9]+", extended: true}, []string{"aaab", "id=42"}},
+		{"ERE word boundaries", grepOptions{pattern: `\<bar\>`, extended: true}, []string{"foo bar"}},
+		{"Perl-like", grepOptions{pattern: `\d{2}`, perl: true}, []string{"id=42"}},
+		{"Fixed string is a substring", grepOptions{pattern: "a.b", fixed: true}, []string{"a.b"}},
+		{"Dot matches any char", grepOptions{pattern: "a.b"}, []string{"aaab", "a+b (x)", "a.b", "a bc"}},
+		{"Word match", grepOptions{pattern: "foo", wordRegexp: true}, []string{"foo bar"}},
+		{"Word match with fixed string", grepOptions{pattern: "b", fixed: true, wordRegexp: true}, []string{"a+b (x)", "a.b"}},
+		{
+			"Word match retries a shorter match",
+			grepOptions{pattern: "a b|a", extended: true, wordRegexp: true},
+			[]string{"a+b (x)", "a.b", "a bc"},
+		},
+		{
+			"Word match retries a shorter fixed string",
+			grepOptions{patterns: []string{"a b", "a"}, patternsSet: true, fixed: true, wordRegexp: true},
+			[]string{"a+b (x)", "a.b", "a bc"},
+		},
+		{"BRE interval without lower bound", grepOptions{pattern: `^a\{,3\}b$`}, []string{"aaab"}},
+		{"ERE interval without lower bound", grepOptions{pattern: "^a{,3}b$", extended: true}, []string{"aaab"}},
+		{"Line match", grepOptions{pattern: "foo.*", lineRegexp: true}, []string{"foo bar", "foobar"}},
+		{"Fixed line match", grepOptions{pattern: "foo", fixed: true, lineRegexp: true}, nil},
+		{"Fixed ignore case", grepOptions{pattern: "FOO", fixed: true, ignoreCase: true}, []string{"foo bar", "foobar"}},
+		{
+			"Multiple patterns",
+			grepOptions{patterns: []string{"aaab", "id"}, patternsSet: true},
+			[]string{"aaab", "id=42"},
+		},
+		{
+			"Multiple fixed patterns",
+			grepOptions{patterns: []string{"bar", "a.b"}, patternsSet: true, fixed: true},
+			[]string{"foo bar", "foobar", "a.b"},
+		},
+		{"Pattern with newline", grepOptions{pattern: "aaab\nid"}, []string{"aaab", "id=42"}},
+		{"No patterns match nothing", grepOptions{patternsSet: true}, nil},
+		{"Empty pattern matches everything", grepOptions{pattern: ""}, lines},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matches, _, err := grep(lines, test.options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(matches, test.expected) {
+				t.Errorf("Expected %q, got %q", test.expected, matches)
+			}
+		})
+	}
+}
+
+// Тест для ошибок в паттернах
+func TestGrepPatternErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		options grepOptions
+	}{
+		{"Unmatched bracket", grepOptions{pattern: "[abc"}},
+		{"Back-reference", grepOptions{pattern: `\(a\)\1`}},
+		{"Trailing backslash", grepOptions{pattern: `abc\`}},
+		{"Unmatched paren in ERE", grepOptions{pattern: "(abc", extended: true}},
+		{"Conflicting matchers", grepOptions{pattern: "abc", extended: true, fixed: true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := grep([]string{"abc"}, test.options); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+// Тест для чтения паттернов из файла (-f)
+func TestReadPatternFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "patterns.txt")
+	if err := os.WriteFile(name, []byte("foo\n\nbar\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	patterns, err := readPatternFile(name)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"foo", "", "bar"}
+	if !reflect.DeepEqual(patterns, expected) {
+		t.Errorf("Expected %q, got %q", expected, patterns)
+	}
+
+	if _, err := readPatternFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Expected error for missing file, got nil")
+	}
+}
+
+// Функция создает searcher, который пишет в w
+func newTestSearcher(t *testing.T, options grepOptions, w io.Writer, lineBuffered bool) *searcher {
+	t.Helper()
+	m, err := newMatcher(options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return newSearcher(options, m, nil, w, lineBuffered)
+}
+
+// chanWriter передает каждую запись в канал
+type chanWriter chan string
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+// Тест: совпадения печатаются до окончания ввода (как в tail -f | grep)
+func TestSearchStreaming(t *testing.T) {
+	pr, pw := io.Pipe()
+	out := make(chanWriter, 10)
+	s := newTestSearcher(t, grepOptions{pattern: "error", lineNum: true}, out, true)
+
+	done := make(chan error)
+	go func() {
+		_, err := s.search(pr, stdinName)
+		done <- err
+	}()
+
+	pw.Write([]byte("ok\nerror: disk full\n"))
+	select {
+	case line := <-out:
+		if line != "2:error: disk full\n" {
+			t.Errorf("Expected first match, got %q", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Match was not printed before input was closed")
+	}
+
+	pw.Write([]byte("another error"))
+	pw.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if line := <-out; line != "3:another error\n" {
+		t.Errorf("Expected last line without newline to match, got %q", line)
+	}
+}
+
+// Тест для строк длиннее буфера bufio.Scanner
+func TestSearchLongLines(t *testing.T) {
+	long := strings.Repeat("x", 3*readBufferSize) + "needle" + strings.Repeat("y", 1000)
+	input := "short\n" + long + "\nneedle\n"
+
+	var buf bytes.Buffer
+	s := newTestSearcher(t, grepOptions{pattern: "needle"}, &buf, false)
+	n, err := s.search(strings.NewReader(input), stdinName)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.out.flush()
+
+	if n != 2 {
+		t.Errorf("Expected 2 matches, got %d", n)
+	}
+	if buf.String() != long+"\nneedle\n" {
+		t.Errorf("Long line was not printed intact, got %d bytes", buf.Len())
+	}
+}
+
+// Тест для контекста -B: в памяти хранятся только последние строки
+func TestSearchBeforeRing(t *testing.T) {
+	var input strings.Builder
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(&input, "line %d\n", i)
+	}
+	input.WriteString("match\n")
+
+	var buf bytes.Buffer
+	s := newTestSearcher(t, grepOptions{pattern: "match", before: 3, lineNum: true}, &buf, false)
+	if _, err := s.search(strings.NewReader(input.String()), stdinName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.out.flush()
+
+	expected := "998-line 998\n999-line 999\n1000-line 1000\n1001:match\n"
+	if buf.String() != expected {
+		t.Errorf("Expected output:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+// Функция создает файлы во временной директории и возвращает их пути
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// Функция запускает поиск по файлам и возвращает вывод
+func runSearchFiles(t *testing.T, options grepOptions, files []string) string {
+	t.Helper()
+	m, err := newMatcher(options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	s := newSearcher(options, m, files, &buf, false)
+	if _, err := searchFiles(files, s); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.out.flush()
+	return buf.String()
+}
+
+// Тест для вывода по файлам: -H, -h, -l, -L, -c и нумерация строк в каждом файле
+func TestGrepPerFileReporting(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"a.txt": "error one\nok\nerror two\n",
+		"b.txt": "ok\nok\n",
+		"c.txt": "ok\nerror three\n",
+	})
+	a, b, c := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "c.txt")
+
+	tests := []struct {
+		name     string
+		options  grepOptions
+		files    []string
+		expected string
+	}{
+		{
+			name:     "Single file has no prefix",
+			options:  grepOptions{pattern: "error", lineNum: true},
+			files:    []string{a},
+			expected: "1:error one\n3:error two\n",
+		},
+		{
+			name:     "Multiple files have prefixes and own line numbers",
+			options:  grepOptions{pattern: "error", lineNum: true},
+			files:    []string{a, b, c},
+			expected: a + ":1:error one\n" + a + ":3:error two\n" + c + ":2:error three\n",
+		},
+		{
+			name:     "Force file name",
+			options:  grepOptions{pattern: "error", withFilename: true},
+			files:    []string{c},
+			expected: c + ":error three\n",
+		},
+		{
+			name:     "Suppress file names",
+			options:  grepOptions{pattern: "three", noFilename: true},
+			files:    []string{a, c},
+			expected: "error three\n",
+		},
+		{
+			name:     "Count per file",
+			options:  grepOptions{pattern: "error", count: true},
+			files:    []string{a, b, c},
+			expected: a + ":2\n" + b + ":0\n" + c + ":1\n",
+		},
+		{
+			name:     "Count for single file",
+			options:  grepOptions{pattern: "error", count: true},
+			files:    []string{a},
+			expected: "2\n",
+		},
+		{
+			name:     "Files with matches",
+			options:  grepOptions{pattern: "error", filesWithMatches: true},
+			files:    []string{a, b, c},
+			expected: a + "\n" + c + "\n",
+		},
+		{
+			name:     "Files without match",
+			options:  grepOptions{pattern: "error", filesWithoutMatch: true},
+			files:    []string{a, b, c},
+			expected: b + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := runSearchFiles(t, test.options, test.files)
+			if output != test.expected {
+				t.Errorf("Expected output:\n%s\nGot:\n%s", test.expected, output)
+			}
+		})
+	}
+}
+
+// Тест для рекурсивного поиска с фильтрами, .gitignore и двоичными файлами
+func TestGrepRecursive(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"a.txt":          "match a\n",
+		"bin.dat":        "match\x00binary\n",
+		"keep.log":       "match keep\n",
+		"skip.log":       "match skip\n",
+		"sub/b.txt":      "no\nmatch b\n",
+		"sub/deep/c.go":  "match c\n",
+		"vendor/v.txt":   "match vendor\n",
+		".gitignore":     "*.log\n!keep.log\n/vendor/\n",
+		"sub/.gitignore": "deep/\n",
+	})
+	path := func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(name))
+	}
+
+	tests := []struct {
+		name     string
+		options  grepOptions
+		expected []string
+	}{
+		{
+			name:    "All files",
+			options: grepOptions{pattern: "match", recursive: true},
+			expected: []string{
+				path("a.txt") + ":match a",
+				"Binary file " + path("bin.dat") + " matches",
+				path("keep.log") + ":match keep",
+				path("skip.log") + ":match skip",
+				path("sub/b.txt") + ":match b",
+				path("sub/deep/c.go") + ":match c",
+				path("vendor/v.txt") + ":match vendor",
+			},
+		},
+		{
+			name:    "Include",
+			options: grepOptions{pattern: "match", recursive: true, include: stringList{"*.txt", "*.go"}},
+			expected: []string{
+				path("a.txt") + ":match a",
+				path("sub/b.txt") + ":match b",
+				path("sub/deep/c.go") + ":match c",
+				path("vendor/v.txt") + ":match vendor",
+			},
+		},
+		{
+			name: "Exclude and exclude-dir",
+			options: grepOptions{
+				pattern: "match", recursive: true, filesWithMatches: true,
+				exclude: stringList{"*.log", "*.dat"}, excludeDir: stringList{"vendor", "de*"},
+			},
+			expected: []string{path("a.txt"), path("sub/b.txt")},
+		},
+		{
+			name:    "Gitignore",
+			options: grepOptions{pattern: "match", recursive: true, gitignore: true, filesWithMatches: true},
+			expected: []string{path("a.txt"), path("bin.dat"), path("keep.log"), path("sub/b.txt")},
+		},
+		{
+			name:    "Binary files as text",
+			options: grepOptions{pattern: "binary", recursive: true, binaryFiles: binaryText},
+			expected: []string{path("bin.dat") + ":match\x00binary"},
+		},
+		{
+			name:     "Binary files without match",
+			options:  grepOptions{pattern: "binary", recursive: true, binaryFiles: binaryWithoutMatch},
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := runSearchFiles(t, test.options, []string{dir})
+			var lines []string
+			if output != "" {
+				lines = strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+			}
+			if !reflect.DeepEqual(lines, test.expected) {
+				t.Errorf("Expected %q, got %q", test.expected, lines)
+			}
+		})
+	}
+}
+
+// Тест для символических ссылок: -r идет только по ссылкам из командной строки, -R - по всем
+func TestGrepRecursiveSymlinks(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"root/a.txt":  "match a\n",
+		"other/b.txt": "match b\n",
+	})
+	root := filepath.Join(dir, "root")
+	if err := os.Symlink(filepath.Join(dir, "other"), filepath.Join(root, "link")); err != nil {
+		t.Skipf("Symlinks are not supported: %v", err)
+	}
+	// ссылка на саму себя не должна зациклить -R
+	if err := os.Symlink(root, filepath.Join(root, "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	output := runSearchFiles(t, grepOptions{pattern: "match", recursive: true, filesWithMatches: true}, []string{root})
+	if expected := filepath.Join(root, "a.txt") + "\n"; output != expected {
+		t.Errorf("Expected output:\n%s\nGot:\n%s", expected, output)
+	}
+
+	output = runSearchFiles(t, grepOptions{pattern: "match", dereferenceRecursive: true, filesWithMatches: true}, []string{root})
+	expected := filepath.Join(root, "a.txt") + "\n" + filepath.Join(root, "link", "b.txt") + "\n"
+	if output != expected {
+		t.Errorf("Expected output:\n%s\nGot:\n%s", expected, output)
+	}
+}
+
+// Тест для разбора правил .gitignore
+func TestParseIgnoreRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		path    string
+		isDir   bool
+		matches bool
+	}{
+		{"*.log", "a.log", false, true},
+		{"*.log", "sub/deep/a.log", false, true},
+		{"*.log", "a.txt", false, false},
+		{"/build", "build", true, true},
+		{"/build", "sub/build", true, false},
+		{"docs/*.md", "docs/a.md", false, true},
+		{"docs/*.md", "docs/sub/a.md", false, false},
+		{"docs/**/*.md", "docs/sub/deep/a.md", false, true},
+		{"**/cache", "x/y/cache", true, true},
+		{"tmp/", "tmp", false, false},
+		{"tmp/", "tmp", true, true},
+		{"file[0-9].txt", "file7.txt", false, true},
+		{"file[!0-9].txt", "file7.txt", false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rule+" "+test.path, func(t *testing.T) {
+			rule, ok := parseIgnoreRule(test.rule)
+			if !ok {
+				t.Fatal("Expected rule to be parsed")
+			}
+			matches := rule.re.MatchString(test.path) && (!rule.dirOnly || test.isDir)
+			if matches != test.matches {
+				t.Errorf("Expected match=%v, got %v", test.matches, matches)
+			}
+		})
+	}
+
+	for _, line := range []string{"", "   ", "# comment"} {
+		if _, ok := parseIgnoreRule(line); ok {
+			t.Errorf("Expected %q to be skipped", line)
+		}
+	}
+}
+
+// Тест для -m: поиск останавливается после NUM совпадений, контекст после последнего печатается
+func TestGrepMaxCount(t *testing.T) {
+	lines := []string{"a1", "b1", "a2", "b2", "a3", "b3"}
+
+	tests := []struct {
+		name     string
+		options  grepOptions
+		expected []string
+		total    int
+	}{
+		{
+			name:     "Stop after two matches",
+			options:  grepOptions{pattern: "a", maxCount: 2},
+			expected: []string{"a1", "a2"},
+			total:    2,
+		},
+		{
+			name:     "After context of the last match",
+			options:  grepOptions{pattern: "a", maxCount: 2, after: 2},
+			expected: []string{"a1", "b1", "a2", "b2", "a3"},
+			total:    2,
+		},
+		{
+			name:     "Count is limited too",
+			options:  grepOptions{pattern: "a", maxCount: 1, count: true},
+			expected: nil,
+			total:    1,
+		},
+		{
+			name:     "Zero means no limit",
+			options:  grepOptions{pattern: "b"},
+			expected: []string{"b1", "b2", "b3"},
+			total:    3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matches, total, err := grep(lines, test.options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(matches, test.expected) || total != test.total {
+				t.Errorf("Expected %v (%d), got %v (%d)", test.expected, test.total, matches, total)
+			}
+		})
+	}
+}
+
+// Тест для -j: параллельный поиск печатает то же, что и последовательный, в порядке файлов
+func TestGrepParallel(t *testing.T) {
+	files := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		var sb strings.Builder
+		for j := 0; j < 50; j++ {
+			fmt.Fprintf(&sb, "file %d line %d\n", i, j)
+		}
+		files[fmt.Sprintf("f%02d.txt", i)] = sb.String()
+	}
+	dir := writeTestFiles(t, files)
+
+	optionSets := []grepOptions{
+		{pattern: "line 1", lineNum: true, recursive: true},
+		{pattern: "line 4", after: 1, recursive: true},
+		{pattern: "file 1[0-9]", count: true, recursive: true},
+		{pattern: "file 7 ", filesWithMatches: true, recursive: true},
+		{pattern: "line", maxCount: 3, recursive: true},
+	}
+	for _, options := range optionSets {
+		sequential := runSearchFiles(t, options, []string{dir})
+		options.jobs = 4
+		parallel := runSearchFiles(t, options, []string{dir})
+		if parallel != sequential {
+			t.Errorf("Options %+v: expected output:\n%s\nGot:\n%s", options, sequential, parallel)
+		}
+	}
+}
+
+// Тест для -q: ничего не печатается, поиск останавливается на первом совпадении
+func TestGrepQuiet(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"a.txt": "nothing\n",
+		"b.txt": "match\n",
+	})
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	missing := filepath.Join(dir, "missing.txt")
+
+	for _, jobs := range []int{1, 4} {
+		options := grepOptions{pattern: "match", quiet: true, jobs: jobs}
+		m, err := newMatcher(options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// после совпадения в b.txt отсутствующий файл уже не открывается
+		var buf bytes.Buffer
+		s := newSearcher(options, m, nil, &buf, false)
+		total, err := searchFiles([]string{a, b, missing}, s)
+		s.out.flush()
+		if jobs == 1 && err != nil {
+			t.Errorf("jobs=%d: unexpected error: %v", jobs, err)
+		}
+		if total == 0 {
+			t.Errorf("jobs=%d: expected a match", jobs)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("jobs=%d: expected no output, got %q", jobs, buf.String())
+		}
+	}
+}
+
+// Тест для -j -q: после совпадения поиск не ждет операнд, чтение которого блокируется
+func TestGrepParallelQuietBlockingOperand(t *testing.T) {
+    dir := writeTestFiles(t, map[string]string{"m.txt": "foo\n"})
+
+    // стандартный ввод, в который никто не пишет; закрываем его в конце, чтобы отпустить воркер
+    r, w := io.Pipe()
+    defer w.Close()
+
+    status := make(chan int, 1)
+    go func() {
+        options := grepOptions{pattern: "foo", quiet: true, jobs: 2}
+        status <- run(options, []string{filepath.Join(dir, "m.txt"), "-"}, r, io.Discard, io.Discard)
+    }()
+
+    select {
+    case got := <-status:
+        if got != exitMatch {
+            t.Errorf("Expected status %d, got %d", exitMatch, got)
+        }
+    case <-time.After(2 * time.Second):
+        t.Fatal("Expected -q to return after the first match")
+    }
+}
+
+// Тест остановки поиска: после закрытия stop бесконечный вход больше не читается
+func TestSearchStop(t *testing.T) {
+    options := grepOptions{pattern: "x", count: true}
+    m, err := newMatcher(options)
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
+    s := newSearcher(options, m, nil, io.Discard, false)
+    stop := make(chan struct{})
+    close(stop)
+    s.stop = stop
+
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        s.search(endlessReader{}, stdinName)
+    }()
+    select {
+    case <-done:
+    case <-time.After(2 * time.Second):
+        t.Fatal("Expected the search to stop")
+    }
+}
+
+// endlessReader - вход, который никогда не заканчивается
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+    for i := range p {
+        p[i] = 'x'
+        if i%80 == 79 {
+            p[i] = '\n'
+        }
+    }
+    return len(p), nil
+}
+
+// Тест для формата контекста GNU: "N:" для совпадений, "N-" для контекста и разделители групп
+func TestGrepContextGroups(t *testing.T) {
+	lines := []string{"a", "match 1", "b", "c", "d", "e", "match 2", "f", "match 3", "g"}
+
+	tests := []struct {
+		name     string
+		options  grepOptions
+		expected []string
+	}{
+		{
+			name:     "Separator between distant groups",
+			options:  grepOptions{pattern: "match", context: 1, lineNum: true},
+			expected: []string{"1-a", "2:match 1", "3-b", "--", "6-e", "7:match 2", "8-f", "9:match 3", "10-g"},
+		},
+		{
+			name:     "Adjacent groups are merged",
+			options:  grepOptions{pattern: "match", after: 3},
+			expected: []string{"match 1", "b", "c", "d", "--", "match 2", "f", "match 3", "g"},
+		},
+		{
+			name:     "Custom separator",
+			options:  grepOptions{pattern: "match", before: 1, groupSeparator: "==", groupSeparatorSet: true},
+			expected: []string{"a", "match 1", "==", "e", "match 2", "f", "match 3"},
+		},
+		{
+			name:     "Empty separator",
+			options:  grepOptions{pattern: "match", before: 1, groupSeparatorSet: true},
+			expected: []string{"a", "match 1", "", "e", "match 2", "f", "match 3"},
+		},
+		{
+			name:     "No separator",
+			options:  grepOptions{pattern: "match", before: 1, noGroupSeparator: true},
+			expected: []string{"a", "match 1", "e", "match 2", "f", "match 3"},
+		},
+		{
+			name:     "No separator without context",
+			options:  grepOptions{pattern: "match", lineNum: true},
+			expected: []string{"2:match 1", "7:match 2", "9:match 3"},
+		},
+		{
+			name:     "Context overridden by -A",
+			options:  grepOptions{pattern: "match 2", context: 2, after: 1},
+			expected: []string{"d", "e", "match 2", "f"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matches, _, err := grep(lines, test.options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(matches, test.expected) {
+				t.Errorf("Expected %q, got %q", test.expected, matches)
+			}
+		})
+	}
+}
+
+// Тест для разбора -C вместе с -A и -B
+func TestResolveContext(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       grepOptions
+		set           map[string]bool
+		after, before int
+	}{
+		{"Only -C", grepOptions{context: 2}, map[string]bool{"C": true}, 2, 2},
+		{"-C with -A", grepOptions{context: 3, after: 1}, map[string]bool{"C": true, "A": true}, 1, 3},
+		{"-C with -A 0", grepOptions{context: 3}, map[string]bool{"C": true, "A": true}, 0, 3},
+		{"-A and -B", grepOptions{after: 1, before: 2}, map[string]bool{"A": true, "B": true}, 1, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := test.options
+			options.resolveContext(test.set)
+			if options.afterContext() != test.after || options.beforeContext() != test.before {
+				t.Errorf("Expected -A %d -B %d, got -A %d -B %d",
+					test.after, test.before, options.afterContext(), options.beforeContext())
+			}
+		})
+	}
+}
+
+// Тест для разделителей групп и имен файлов при поиске в нескольких файлах
+func TestGrepContextGroupsFiles(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"a.txt": "x\nmatch\ny\n",
+		"b.txt": "match\nz\n",
+	})
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+
+	expected := a + "-1-x\n" + a + ":2:match\n" + a + "-3-y\n--\n" + b + ":1:match\n" + b + "-2-z\n"
+	for _, jobs := range []int{1, 2} {
+		options := grepOptions{pattern: "match", context: 1, lineNum: true, jobs: jobs}
+		output := runSearchFiles(t, options, []string{a, b})
+		if output != expected {
+			t.Errorf("jobs=%d: expected output:\n%s\nGot:\n%s", jobs, expected, output)
+		}
+	}
+}
+
+// Тест для -o, -b и --column
+func TestGrepOnlyMatching(t *testing.T) {
+	lines := []string{"foo bar foo", "xyz", "barfoo"}
+
+	tests := []struct {
+		name     string
+		options  grepOptions
+		expected []string
+	}{
+		{
+			name:     "Each match on its own line",
+			options:  grepOptions{pattern: "fo*"},
+			expected: []string{"foo bar foo", "barfoo"},
+		},
+		{
+			name:     "Only matching",
+			options:  grepOptions{pattern: "fo*", onlyMatching: true, lineNum: true},
+			expected: []string{"1:foo", "1:foo", "3:foo"},
+		},
+		{
+			name:     "Byte offsets of lines",
+			options:  grepOptions{pattern: "bar", byteOffset: true},
+			expected: []string{"0:foo bar foo", "16:barfoo"},
+		},
+		{
+			name:     "Byte offsets of matches",
+			options:  grepOptions{pattern: "foo", onlyMatching: true, byteOffset: true},
+			expected: []string{"0:foo", "8:foo", "19:foo"},
+		},
+		{
+			name:     "Column of the first match",
+			options:  grepOptions{pattern: "bar", column: true, lineNum: true},
+			expected: []string{"1:5:foo bar foo", "3:1:barfoo"},
+		},
+		{
+			name:     "Column of every match with -o",
+			options:  grepOptions{pattern: "foo", column: true, onlyMatching: true},
+			expected: []string{"1:foo", "9:foo", "4:foo"},
+		},
+		{
+			name:     "Context is not printed with -o",
+			options:  grepOptions{pattern: "xyz", onlyMatching: true, context: 1},
+			expected: []string{"xyz"},
+		},
+		{
+			name:     "Nothing to print with -o -v",
+			options:  grepOptions{pattern: "foo", onlyMatching: true, invert: true},
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matches, _, err := grep(lines, test.options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(matches, test.expected) {
+				t.Errorf("Expected %q, got %q", test.expected, matches)
+			}
+		})
+	}
+}
+
+// Тест для подсветки совпадений, имен файлов и номеров строк
+func TestGrepColor(t *testing.T) {
+	lines := []string{"a foo b", "ctx"}
+	colors := defaultColors()
+
+	tests := []struct {
+		name     string
+		options  grepOptions
+		expected []string
+	}{
+		{
+			name:     "Matches and line numbers",
+			options:  grepOptions{pattern: "foo", lineNum: true, colors: colors},
+			expected: []string{"\x1b[32m\x1b[K1\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[Ka \x1b[01;31m\x1b[Kfoo\x1b[m\x1b[K b"},
+		},
+		{
+			name:     "Context line is not highlighted",
+			options:  grepOptions{pattern: "foo", after: 1, colors: colors},
+			expected: []string{"a \x1b[01;31m\x1b[Kfoo\x1b[m\x1b[K b", "ctx"},
+		},
+		{
+			name:     "Matches in context lines with -v",
+			options:  grepOptions{pattern: "foo", invert: true, before: 1, colors: parseGrepColors("mc=04")},
+			expected: []string{"a \x1b[04m\x1b[Kfoo\x1b[m\x1b[K b", "ctx"},
+		},
+		{
+			name:     "GREP_COLORS without clearing",
+			options:  grepOptions{pattern: "oo", onlyMatching: true, colors: parseGrepColors("ms=01;32:ne")},
+			expected: []string{"\x1b[01;32moo\x1b[m"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matches, _, err := grep(lines, test.options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(matches, test.expected) {
+				t.Errorf("Expected %q, got %q", test.expected, matches)
+			}
+		})
+	}
+}
+
+// Тест для разбора GREP_COLORS и --color
+func TestParseGrepColors(t *testing.T) {
+	c := parseGrepColors("mt=01;34:fn=:sl=7:rv:bad=x:ln=oops")
+	if c.selectedMatch != "01;34" || c.contextMatch != "01;34" {
+		t.Errorf("mt should set both match colors, got %q and %q", c.selectedMatch, c.contextMatch)
+	}
+	if c.fileName != "" || c.lineNum != "32" {
+		t.Errorf("Expected empty fn and default ln, got %q and %q", c.fileName, c.lineNum)
+	}
+	if selected, context := c.lineColors(true); selected != "" || context != "7" {
+		t.Errorf("rv should swap line colors with -v, got %q and %q", selected, context)
+	}
+
+	var f colorFlag
+	for value, expected := range map[string]colorFlag{"true": colorAuto, "always": colorAlways, "none": colorNever} {
+		if err := f.Set(value); err != nil || f != expected {
+			t.Errorf("Set(%q): expected %q, got %q (%v)", value, expected, f, err)
+		}
+	}
+	if err := f.Set("sometimes"); err == nil {
+		t.Error("Expected an error for an invalid --color value")
+	}
+	if !colorFlag(colorAlways).enabled(false) || colorFlag(colorNever).enabled(true) {
+		t.Error("Unexpected result of enabled()")
+	}
+}
+
+// Функция разбирает вывод --json на события, обнуляя время, чтобы вывод можно было сравнивать
+func decodeEvents(t *testing.T, output string) []jsonEvent {
+	t.Helper()
+	var events []jsonEvent
+	dec := json.NewDecoder(strings.NewReader(output))
+	for dec.More() {
+		var raw struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := dec.Decode(&raw); err != nil {
+			t.Fatalf("Invalid JSON output: %v\n%s", err, output)
+		}
+
+		var data any
+		switch raw.Type {
+		case "begin":
+			data = &jsonBegin{}
+		case "match", "context":
+			data = &jsonLine{}
+		case "end":
+			data = &jsonEnd{}
+		case "summary":
+			data = &jsonSummary{}
+		default:
+			t.Fatalf("Unexpected event type %q", raw.Type)
+		}
+		if err := json.Unmarshal(raw.Data, data); err != nil {
+			t.Fatalf("Invalid %s event: %v", raw.Type, err)
+		}
+		switch d := data.(type) {
+		case *jsonEnd:
+			d.Stats.Elapsed = jsonDuration{}
+		case *jsonSummary:
+			d.ElapsedTotal, d.Stats.Elapsed = jsonDuration{}, jsonDuration{}
+		}
+		events = append(events, jsonEvent{Type: raw.Type, Data: data})
+	}
+	return events
+}
+
+// Тест для --json: события по файлам, смещения, подсовпадения и статистика
+func TestGrepJSON(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"a.txt": "foo bar foo\nxyz\nbarfoo\n",
+		"b.txt": "nothing\n",
+		"c.txt": "\xff foo\n",
+	})
+	a, b, c := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "c.txt")
+	text := func(s string) jsonText { return newJSONText(s) }
+
+	options := grepOptions{pattern: "foo", after: 1, json: true}
+	events := decodeEvents(t, runSearchFiles(t, options, []string{a, b, c}))
+
+	expected := []jsonEvent{
+		{Type: "begin", Data: &jsonBegin{Path: text(a)}},
+		{Type: "match", Data: &jsonLine{Path: text(a), Lines: text("foo bar foo"), LineNumber: 1, AbsoluteOffset: 0,
+			Submatches: []jsonSubmatch{{Match: text("foo"), Start: 0, End: 3}, {Match: text("foo"), Start: 8, End: 11}}}},
+		{Type: "context", Data: &jsonLine{Path: text(a), Lines: text("xyz"), LineNumber: 2, AbsoluteOffset: 12,
+			Submatches: []jsonSubmatch{}}},
+		{Type: "match", Data: &jsonLine{Path: text(a), Lines: text("barfoo"), LineNumber: 3, AbsoluteOffset: 16,
+			Submatches: []jsonSubmatch{{Match: text("foo"), Start: 3, End: 6}}}},
+		{Type: "end", Data: &jsonEnd{Path: text(a), Stats: jsonStats{
+			Searches: 1, SearchesWithMatch: 1, BytesSearched: 23, MatchedLines: 2, Matches: 3}}},
+		{Type: "begin", Data: &jsonBegin{Path: text(c)}},
+		{Type: "match", Data: &jsonLine{Path: text(c), Lines: text("\xff foo"), LineNumber: 1, AbsoluteOffset: 0,
+			Submatches: []jsonSubmatch{{Match: text("foo"), Start: 2, End: 5}}}},
+		{Type: "end", Data: &jsonEnd{Path: text(c), Stats: jsonStats{
+			Searches: 1, SearchesWithMatch: 1, BytesSearched: 6, MatchedLines: 1, Matches: 1}}},
+		{Type: "summary", Data: &jsonSummary{Stats: jsonStats{
+			Searches: 3, SearchesWithMatch: 2, BytesSearched: 37, MatchedLines: 3, Matches: 4}}},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		got, _ := json.Marshal(events)
+		want, _ := json.Marshal(expected)
+		t.Errorf("Expected events:\n%s\nGot:\n%s", want, got)
+	}
+	if line := events[6].Data.(*jsonLine); line.Lines.Bytes == nil {
+		t.Error("Invalid UTF-8 should be encoded as bytes")
+	}
+
+	// параллельный поиск дает те же события
+	options.jobs = 3
+	parallel := decodeEvents(t, runSearchFiles(t, options, []string{a, b, c}))
+	if !reflect.DeepEqual(parallel, expected) {
+		got, _ := json.Marshal(parallel)
+		t.Errorf("Parallel search: unexpected events:\n%s", got)
+	}
+}
+
+// Тест для несовместимых с --json флагов
+func TestGrepJSONOptions(t *testing.T) {
+	for _, options := range []grepOptions{
+		{json: true, count: true},
+		{json: true, filesWithMatches: true},
+		{json: true, quiet: true},
+	} {
+		options.jobs = 1
+		if err := options.validate(); err == nil {
+			t.Errorf("Expected an error for %+v", options)
+		}
+	}
+}
+
+// bzip2Data - "error one\nok\n", сжатое bzip2 (в стандартной библиотеке нет компрессора bzip2)
+const bzip2Data = "\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\xf5\xcc\x7a\xb1\x00\x00\x02\x51\x80\x00\x10\x40\x00" +
+	"\x02\x09\x90\x00\x20\x00\x31\x0c\x01\x0d\x3d\x08\x16\x3b\x8a\xb1\x4f\x17\x72\x45\x38\x50\x90\xf5\xcc\x7a\xb1"
+
+// Функция сжимает данные gzip или zlib
+func compressData(t *testing.T, data string, gz bool) string {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser = zlib.NewWriter(&buf)
+	if gz {
+		w = gzip.NewWriter(&buf)
+	}
+	if _, err := io.WriteString(w, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// Тест для -z: сжатые файлы распознаются по расширению и по содержимому, в том числе при рекурсивном поиске
+func TestGrepSearchZip(t *testing.T) {
+	content := "error one\nok\n"
+	dir := writeTestFiles(t, map[string]string{
+		"plain.log":        "ok\nerror plain\n",
+		"logs/app.log.gz":  compressData(t, content, true),
+		"logs/app.log.1":   compressData(t, "error gzip without extension\n", true),
+		"logs/app.log.bz2": bzip2Data,
+		"logs/app.zz":      compressData(t, content, false),
+		"logs/app.zlib.1":  compressData(t, "error zlib without extension\n", false),
+	})
+	path := func(name string) string { return filepath.Join(dir, filepath.FromSlash(name)) }
+
+	options := grepOptions{pattern: "error", recursive: true, searchZip: true, lineNum: true}
+	output := runSearchFiles(t, options, []string{dir})
+	expected := path("logs/app.log.1") + ":1:error gzip without extension\n" +
+		path("logs/app.log.bz2") + ":1:error one\n" +
+		path("logs/app.log.gz") + ":1:error one\n" +
+		path("logs/app.zlib.1") + ":1:error zlib without extension\n" +
+		path("logs/app.zz") + ":1:error one\n" +
+		path("plain.log") + ":2:error plain\n"
+	if output != expected {
+		t.Errorf("Expected output:\n%s\nGot:\n%s", expected, output)
+	}
+
+	// без -z сжатый файл - это двоичные данные
+	options.searchZip = false
+	output = runSearchFiles(t, options, []string{path("logs/app.log.gz")})
+	if strings.Contains(output, "error one") {
+		t.Errorf("Compressed file should not be decompressed without -z, got %q", output)
+	}
+}
+
+// Тест для ошибки в сжатом файле
+func TestGrepSearchZipCorrupted(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{"bad.gz": "not gzip at all\n"})
+	options := grepOptions{pattern: "gzip", searchZip: true}
+	m, err := newMatcher(options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s := newSearcher(options, m, nil, io.Discard, false)
+	if _, err := s.searchFile(filepath.Join(dir, "bad.gz")); err == nil {
+		t.Error("Expected an error for a corrupted gzip file")
+	}
+}
+
+// Тест для определения формата по первым байтам
+func TestDetectCompression(t *testing.T) {
+	tests := []struct {
+		head     string
+		expected compression
+	}{
+		{"\x1f\x8b\x08\x00", compressionGzip},
+		{"BZh9", compressionBzip2},
+		{"BZhx", compressionNone},
+		{"\x78\x9c\x4b\x2d", compressionZlib},
+		{"x^2 + y", compressionNone},
+		{"plain text", compressionNone},
+		{"", compressionNone},
+	}
+	for _, test := range tests {
+		if got := detectCompression([]byte(test.head)); got != test.expected {
+			t.Errorf("detectCompression(%q): expected %d, got %d", test.head, test.expected, got)
+		}
+	}
+}
+
+// Тест для кодов возврата и продолжения поиска после ошибок файлов
+func TestRunExitStatus(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"a.txt": "error one\nok\n",
+		"b.txt": "ok\n",
+	})
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	missing := filepath.Join(dir, "missing.txt")
+
+	tests := []struct {
+		name     string
+		options  grepOptions
+		files    []string
+		status   int
+		output   string
+		messages bool // ожидаются сообщения об ошибках
+	}{
+		{
+			name:    "Match",
+			options: grepOptions{pattern: "error"},
+			files:   []string{a, b},
+			status:  exitMatch,
+			output:  a + ":error one\n",
+		},
+		{
+			name:    "No match",
+			options: grepOptions{pattern: "nothing"},
+			files:   []string{a, b},
+			status:  exitNoMatch,
+		},
+		{
+			name:     "Missing file does not stop the search",
+			options:  grepOptions{pattern: "error"},
+			files:    []string{missing, a},
+			status:   exitError,
+			output:   a + ":error one\n",
+			messages: true,
+		},
+		{
+			name:    "Suppressed messages still give an error status",
+			options: grepOptions{pattern: "error", noMessages: true},
+			files:   []string{missing, a},
+			status:  exitError,
+			output:  a + ":error one\n",
+		},
+		{
+			name:    "Quiet match wins over errors",
+			options: grepOptions{pattern: "error", quiet: true, noMessages: true},
+			files:   []string{missing, a},
+			status:  exitMatch,
+		},
+		{
+			name:     "Directory without -r",
+			options:  grepOptions{pattern: "error"},
+			files:    []string{dir, a},
+			status:   exitError,
+			output:   a + ":error one\n",
+			messages: true,
+		},
+		{
+			name:     "Invalid pattern",
+			options:  grepOptions{pattern: `a\`},
+			files:    []string{a},
+			status:   exitError,
+			messages: true,
+		},
+		{
+			name:     "Parallel search continues after errors",
+			options:  grepOptions{pattern: "ok", jobs: 2},
+			files:    []string{a, missing, b},
+			status:   exitError,
+			output:   a + ":ok\n" + b + ":ok\n",
+			messages: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			status := run(test.options, test.files, strings.NewReader(""), &stdout, &stderr)
+			if status != test.status {
+				t.Errorf("Expected status %d, got %d (stderr: %q)", test.status, status, stderr.String())
+			}
+			if stdout.String() != test.output {
+				t.Errorf("Expected output:\n%s\nGot:\n%s", test.output, stdout.String())
+			}
+			if (stderr.Len() > 0) != test.messages {
+				t.Errorf("Unexpected error messages: %q", stderr.String())
+			}
+		})
+	}
+}
+
+// failingWriter - вывод, в который нельзя писать
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+// Тест для ошибки вывода: она прерывает поиск
+func TestRunOutputError(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{"a.txt": "error\n"})
+	var stderr bytes.Buffer
+	status := run(grepOptions{pattern: "error"}, []string{filepath.Join(dir, "a.txt")}, strings.NewReader(""), failingWriter{}, &stderr)
+	if status != exitError || !strings.Contains(stderr.String(), "disk full") {
+		t.Errorf("Expected status %d with the write error, got %d (%q)", exitError, status, stderr.String())
+	}
+}
+
+// Тест для поиска без учета регистра с особыми случаями Unicode: вывод остается исходным
+func TestGrepIgnoreCaseUnicode(t *testing.T) {
+	lines := []string{
+		"ΟΔΥΣΣΕΥΣ",
+		"Straße",
+		"STRAẞE",
+		"100 K", // знак кельвина
+		"Istanbul",
+		"ıspanak",
+	}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected []string
+	}{
+		{"Greek sigma in any form", "οδυσσευς", []string{"ΟΔΥΣΣΕΥΣ"}},
+		{"Final sigma", "ΟΔΥςςΕΥς", []string{"ΟΔΥΣΣΕΥΣ"}},
+		{"Sharp s", "straße", []string{"Straße", "STRAẞE"}},
+		{"Kelvin sign", "100 k", []string{"100 K"}},
+		{"Dotted i", "istanbul", []string{"Istanbul"}},
+		{"Dotless i is a different letter", "ISPANAK", nil},
+	}
+
+	for _, test := range tests {
+		for _, fixed := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s fixed=%v", test.name, fixed), func(t *testing.T) {
+				options := grepOptions{pattern: test.pattern, ignoreCase: true, fixed: fixed}
+				matches, _, err := grep(lines, options)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(matches, test.expected) {
+					t.Errorf("Expected %q, got %q", test.expected, matches)
+				}
+			})
+		}
+	}
+}
+
+// Тест для границ совпадений без учета регистра: руны разной длины в байтах
+func TestFoldMatcherSpans(t *testing.T) {
+	m := newFoldMatcher([]string{"k", "ss"})
+	line := "K-SS-ß-k"
+	expected := [][]int{{0, 3}, {4, 6}, {10, 11}}
+	if spans := m.find(line); !reflect.DeepEqual(spans, expected) {
+		t.Errorf("Expected %v, got %v", expected, spans)
+	}
+
+	lm := newFoldLineMatcher([]string{"Straße"})
+	if !lm.match("STRAẞE") || lm.match("STRASSE") {
+		t.Error("Unexpected result of the -F -x -i matcher")
+	}
+}
+
+// Тест для --smart-case
+func TestGrepSmartCase(t *testing.T) {
+	lines := []string{"Error", "error", "ERROR"}
+
+	tests := []struct {
+		name     string
+		options  grepOptions
+		expected []string
+	}{
+		{"Lowercase pattern ignores case", grepOptions{pattern: "error", smartCase: true}, []string{"Error", "error", "ERROR"}},
+		{"Uppercase letter keeps case", grepOptions{pattern: "Error", smartCase: true}, []string{"Error"}},
+		{"Escapes are not letters", grepOptions{pattern: `\Srror`, smartCase: true, perl: true}, []string{"Error", "error", "ERROR"}},
+		{"Unicode classes are not letters", grepOptions{pattern: `\p{Lu}rror`, smartCase: true, perl: true}, []string{"Error", "error", "ERROR"}},
+		{"Any pattern with uppercase", grepOptions{patterns: []string{"error", "ERR"}, patternsSet: true, smartCase: true, fixed: true}, []string{"error", "ERROR"}},
+		{"-i wins", grepOptions{pattern: "Error", smartCase: true, ignoreCase: true}, []string{"Error", "error", "ERROR"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matches, _, err := grep(lines, test.options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(matches, test.expected) {
+				t.Errorf("Expected %q, got %q", test.expected, matches)
+			}
+		})
+	}
+}
+
+// Тест для Бойера-Мура-Хорспула и Ахо-Корасик: результаты совпадают с простым поиском literalMatcher
+func TestFixedMatchers(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	randomString := func(n int) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = "abc"[rnd.Intn(3)]
+		}
+		return string(b)
+	}
+
+	for i := 0; i < 500; i++ {
+		patterns := make([]string, 1+rnd.Intn(5))
+		for j := range patterns {
+			patterns[j] = randomString(1 + rnd.Intn(4))
+		}
+		reference := newLiteralMatcher(patterns)
+		m := newFixedMatcher(patterns)
+
+		for j := 0; j < 10; j++ {
+			line := randomString(rnd.Intn(30))
+			if m.match(line) != reference.match(line) {
+				t.Fatalf("%T.match(%q) for %q: expected %v", m, line, patterns, reference.match(line))
+			}
+			if spans, expected := m.find(line), reference.find(line); !reflect.DeepEqual(spans, expected) {
+				t.Fatalf("%T.find(%q) for %q: expected %v, got %v", m, line, patterns, expected, spans)
+			}
+		}
+	}
+}
+
+// Тест -F -i: поиск по свернутой копии строки совпадает с посимвольным foldMatcher,
+// в том числе для рун, у которых при свертывании меняется длина в байтах, и некорректного UTF-8
+func TestFoldedMatchers(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	alphabet := []string{"a", "A", "k", "K", "\u212a", "s", "ß", "ẞ", "σ", "ς", "Σ", "\xff", "-"}
+	randomString := func(n int) string {
+		var sb strings.Builder
+		for i := 0; i < n; i++ {
+			sb.WriteString(alphabet[rnd.Intn(len(alphabet))])
+		}
+		return sb.String()
+	}
+
+	for i := 0; i < 500; i++ {
+		patterns := make([]string, 1+rnd.Intn(5))
+		for j := range patterns {
+			patterns[j] = randomString(1 + rnd.Intn(3))
+		}
+		reference := newFoldMatcher(patterns)
+		m := newFoldFixedMatcher(patterns)
+
+		for j := 0; j < 10; j++ {
+			line := randomString(rnd.Intn(20))
+			if m.match(line) != reference.match(line) {
+				t.Fatalf("%T.match(%q) for %q: expected %v", m, line, patterns, reference.match(line))
+			}
+			if spans, expected := m.find(line), reference.find(line); !reflect.DeepEqual(spans, expected) {
+				t.Fatalf("%T.find(%q) for %q: expected %v, got %v", m, line, patterns, expected, spans)
+			}
+		}
+	}
+
+	if _, ok := newFoldFixedMatcher([]string{"id", "name"}).(foldedMatcher); !ok {
+		t.Error("Expected a search in the folded line for several patterns")
+	}
+}
+
+// Тест для выбора алгоритма по числу паттернов
+func TestNewFixedMatcher(t *testing.T) {
+	if _, ok := newFixedMatcher([]string{"id"}).(*horspoolMatcher); !ok {
+		t.Error("Expected Boyer-Moore-Horspool for a single pattern")
+	}
+	if _, ok := newFixedMatcher([]string{"id", "name"}).(*ahoCorasick); !ok {
+		t.Error("Expected Aho-Corasick for several patterns")
+	}
+	if m := newFixedMatcher([]string{"id", ""}); !m.match("anything") {
+		t.Error("Empty pattern should match any line")
+	}
+
+	ac := newAhoCorasick([]string{"he", "she", "his", "hers"})
+	expected := [][]int{{1, 4}, {7, 10}}
+	if spans := ac.find("ushers his"); !reflect.DeepEqual(spans, expected) {
+		t.Errorf("Expected %v, got %v", expected, spans)
+	}
+}
+
+// Функция готовит строки и паттерны для бенчмарков: count идентификаторов, ищется один из последних
+func benchmarkLiteralData(count int) ([]string, []string) {
+	rnd := rand.New(rand.NewSource(1))
+	patterns := make([]string, count)
+	for i := range patterns {
+		patterns[i] = fmt.Sprintf("user-%08d", rnd.Intn(100000000))
+	}
+	lines := make([]string, 1000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("2024-01-01T00:00:%02d level=info request=%d msg=\"request served\" user=user-%08d",
+			i%60, i, rnd.Intn(100000000))
+	}
+	lines[len(lines)-1] += " " + patterns[count-1]
+	return lines, patterns
+}
+
+// Функция прогоняет matcher по строкам
+func benchmarkMatcher(b *testing.B, m matcher, lines []string) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		found := 0
+		for _, line := range lines {
+			if m.match(line) {
+				found++
+			}
+		}
+		if found == 0 {
+			b.Fatal("Expected a match")
+		}
+	}
+}
+
+// Функция строит matcher на регулярном выражении из экранированных паттернов для сравнения с regexp
+func benchmarkRegexpMatcher(b *testing.B, patterns []string) matcher {
+	re, err := compilePatterns(patterns, grepOptions{fixed: true})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return regexMatcher{re: re}
+}
+
+func BenchmarkFixedSingleHorspool(b *testing.B) {
+	lines, patterns := benchmarkLiteralData(1)
+	benchmarkMatcher(b, newFixedMatcher(patterns), lines)
+}
+
+func BenchmarkFixedSingleRegexp(b *testing.B) {
+	lines, patterns := benchmarkLiteralData(1)
+	benchmarkMatcher(b, benchmarkRegexpMatcher(b, patterns), lines)
+}
+
+func BenchmarkFixedManyAhoCorasick(b *testing.B) {
+	lines, patterns := benchmarkLiteralData(5000)
+	benchmarkMatcher(b, newFixedMatcher(patterns), lines)
+}
+
+func BenchmarkFixedManyFold(b *testing.B) {
+	lines, patterns := benchmarkLiteralData(5000)
+	for i := range patterns {
+		patterns[i] = strings.ToUpper(patterns[i])
+	}
+	benchmarkMatcher(b, newFoldFixedMatcher(patterns), lines)
+}
+
+func BenchmarkFixedManyFoldPerRune(b *testing.B) {
+	lines, patterns := benchmarkLiteralData(5000)
+	for i := range patterns {
+		patterns[i] = strings.ToUpper(patterns[i])
+	}
+	benchmarkMatcher(b, newFoldMatcher(patterns), lines)
+}
+
+func BenchmarkFixedManyContains(b *testing.B) {
+	lines, patterns := benchmarkLiteralData(5000)
+	benchmarkMatcher(b, newLiteralMatcher(patterns), lines)
+}
+
+func BenchmarkFixedManyRegexp(b *testing.B) {
+	lines, patterns := benchmarkLiteralData(5000)
+	benchmarkMatcher(b, benchmarkRegexpMatcher(b, patterns), lines)
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// walker превращает операнды командной строки в список файлов для поиска:
+// обходит директории для -r/-R и применяет фильтры --include, --exclude, --exclude-dir и .gitignore
+type walker struct {
+	options grepOptions
+	ignore  *gitignore // nil, если .gitignore не учитывается
+	visited map[string]bool
+	report  func(error) // получает ошибки отдельных файлов и директорий, обход после них продолжается
+}
+
+// Функция создает walker. Ошибки файлов и директорий передаются в report
+func newWalker(options grepOptions, report func(error)) *walker {
+	w := &walker{options: options, visited: make(map[string]bool), report: report}
+	if options.gitignore {
+		w.ignore = newGitignore()
+	}
+	return w
+}
+
+// Функция сообщает, включен ли рекурсивный поиск
+func (o grepOptions) isRecursive() bool {
+	return o.recursive || o.dereferenceRecursive
+}
+
+// walk вызывает fn для каждого файла, который нужно просмотреть для операнда ("-" - стандартный ввод).
+// Возвращает только ошибки fn, ошибки файлов уходят в report
+func (w *walker) walk(operand string, fn func(path string) error) error {
+	if operand == "-" {
+		return fn(operand)
+	}
+
+	// символические ссылки в командной строке разыменовываются всегда
+	info, err := os.Stat(operand)
+	if err != nil {
+		w.report(fileErrorf("could not open file %s: %w", operand, err))
+		return nil
+	}
+	if !info.IsDir() {
+		if !w.includeFile(filepath.Base(operand)) {
+			return nil
+		}
+		return fn(operand)
+	}
+	if !w.options.isRecursive() {
+		w.report(fileErrorf("%s: is a directory", operand))
+		return nil
+	}
+	return w.walkDir(operand, fn)
+}
+
+// Функция рекурсивно обходит директорию
+func (w *walker) walkDir(root string, fn func(path string) error) error {
+	if w.options.dereferenceRecursive {
+		// для -R запоминаем пройденные директории, чтобы не зациклиться на ссылках
+		real, err := filepath.EvalSymlinks(root)
+		if err == nil {
+			if w.visited[real] {
+				return nil
+			}
+			w.visited[real] = true
+		}
+		// WalkDir не разыменовывает корень-ссылку, а со слешем на конце он становится директорией
+		if !strings.HasSuffix(root, string(filepath.Separator)) {
+			root += string(filepath.Separator)
+		}
+	}
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// нечитаемую директорию пропускаем, остальное дерево обходим дальше
+			w.report(fileErrorf("could not read %s: %w", path, err))
+			return nil
+		}
+
+		if d.IsDir() {
+			if path != root && !w.includeDir(path) {
+				return filepath.SkipDir
+			}
+			if err := w.loadIgnore(path); err != nil {
+				w.report(fileError{err})
+			}
+			return nil
+		}
+
+		if d.Type()&fs.ModeSymlink != 0 {
+			// -r не идет по символическим ссылкам внутри дерева, -R идет
+			if !w.options.dereferenceRecursive {
+				return nil
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				w.report(fileErrorf("could not open file %s: %w", path, err))
+				return nil
+			}
+			if info.IsDir() {
+				if !w.includeDir(path) {
+					return nil
+				}
+				return w.walkDir(path, fn)
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+		} else if !d.Type().IsRegular() {
+			// устройства, каналы и сокеты при рекурсивном обходе пропускаем
+			return nil
+		}
+
+		if !w.includeFile(d.Name()) || w.ignored(path, false) {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// Функция проверяет директорию по --exclude-dir и .gitignore
+func (w *walker) includeDir(path string) bool {
+	name := filepath.Base(path)
+	if w.ignore != nil && name == ".git" {
+		return false
+	}
+	for _, glob := range w.options.excludeDir {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return false
+		}
+	}
+	return !w.ignored(path, true)
+}
+
+// Функция проверяет имя файла по --include и --exclude
+func (w *walker) includeFile(name string) bool {
+	for _, glob := range w.options.exclude {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return false
+		}
+	}
+	if len(w.options.include) == 0 {
+		return true
+	}
+	for _, glob := range w.options.include {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Функция загружает .gitignore директории, если он учитывается
+func (w *walker) loadIgnore(dir string) error {
+	if w.ignore == nil {
+		return nil
+	}
+	return w.ignore.load(dir)
+}
+
+// Функция сообщает, исключен ли путь правилами .gitignore
+func (w *walker) ignored(path string, isDir bool) bool {
+	return w.ignore != nil && w.ignore.match(path, isDir)
+}
+
+// Функция проверяет шаблоны --include, --exclude и --exclude-dir
+func validateGlobs(globs []string) error {
+	for _, glob := range globs {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+	}
+	return nil
+}
+
+// ignoreRule - одно правило .gitignore
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool // правило начинается с "!" и возвращает путь обратно
+	dirOnly bool // правило заканчивается на "/" и относится только к директориям
+}
+
+// gitignore - правила .gitignore, загруженные из пройденных директорий
+type gitignore struct {
+	rules map[string][]ignoreRule // директория -> ее правила
+}
+
+// Функция создает пустой набор правил
+func newGitignore() *gitignore {
+	return &gitignore{rules: make(map[string][]ignoreRule)}
+}
+
+// load читает .gitignore из директории, если он есть
+func (g *gitignore) load(dir string) error {
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read %s: %w", file.Name(), err)
+	}
+	g.rules[filepath.Clean(dir)] = rules
+	return nil
+}
+
+// match проверяет путь по правилам всех родительских директорий:
+// правила ближней директории важнее, внутри файла важнее последнее подходящее правило
+func (g *gitignore) match(path string, isDir bool) bool {
+	path = filepath.Clean(path)
+
+	// родительские директории от ближней к дальней
+	var dirs []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+
+	for _, dir := range dirs {
+		rules := g.rules[dir]
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || len(rules) == 0 {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for i := len(rules) - 1; i >= 0; i-- {
+			rule := rules[i]
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.re.MatchString(rel) {
+				return !rule.negate
+			}
+		}
+	}
+	return false
+}
+
+// Функция разбирает строку .gitignore. ok=false для пустых строк и комментариев
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	var rule ignoreRule
+
+	line = strings.TrimRight(line, " ")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+
+	// шаблон со слешем в начале или середине привязан к директории .gitignore,
+	// без слеша - совпадает с именем на любой глубине
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := globToRegexp(line)
+	if anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "^(?:.*/)?" + expr + "$"
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return rule, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// Функция переводит шаблон .gitignore (с поддержкой **) в регулярное выражение
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			sb.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return sb.String()
+}
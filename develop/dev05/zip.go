@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// compression - формат сжатого файла для -z
+type compression int
+
+const (
+	compressionNone compression = iota
+	compressionGzip
+	compressionBzip2
+	compressionZlib
+)
+
+// compressionExts - расширения сжатых файлов
+var compressionExts = map[string]compression{
+	".gz":   compressionGzip,
+	".tgz":  compressionGzip,
+	".bz2":  compressionBzip2,
+	".tbz":  compressionBzip2,
+	".tbz2": compressionBzip2,
+	".zz":   compressionZlib,
+	".zlib": compressionZlib,
+}
+
+// Функция определяет формат по первым байтам файла
+func detectCompression(head []byte) compression {
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return compressionGzip
+	case len(head) >= 4 && bytes.HasPrefix(head, []byte("BZh")) && head[3] >= '1' && head[3] <= '9':
+		return compressionBzip2
+	case len(head) >= 2 && head[0] == 0x78 && (head[1] == 0x01 || head[1] == 0x9c || head[1] == 0xda):
+		// заголовок zlib: deflate с окном 32K, уровни сжатия без словаря
+		return compressionZlib
+	}
+	return compressionNone
+}
+
+// Функция возвращает reader, который распаковывает r, если файл сжат (-z).
+// Формат определяется по расширению имени, а если оно ничего не говорит - по первым байтам.
+// Несжатые данные возвращаются как есть
+func decompress(r io.Reader, name string) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, readBufferSize)
+
+	kind, ok := compressionExts[strings.ToLower(filepath.Ext(name))]
+	if !ok {
+		head, _ := br.Peek(4)
+		kind = detectCompression(head)
+	}
+
+	switch kind {
+	case compressionGzip:
+		return gzip.NewReader(br)
+	case compressionBzip2:
+		return bzip2.NewReader(br), nil
+	case compressionZlib:
+		return zlib.NewReader(br)
+	}
+	return br, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Функция проверяет флаги режима --csv: работает только с полями, разделитель - один символ
+// (по умолчанию запятая), --regex и -z не поддерживаются
+func (o *cutOptions) checkCSV(set map[string]bool) error {
+	switch {
+	case o.mode != unitField:
+		return errors.New("--csv may be specified only when operating on fields")
+	case o.regex || o.zeroTerminated:
+		return errors.New("--csv cannot be combined with --regex or -z")
+	}
+	if !set["d"] {
+		o.delimiter = ","
+	}
+	if utf8.RuneCountInString(o.delimiter) != 1 {
+		return errors.New("the delimiter must be a single character in --csv mode")
+	}
+	if o.outputDelimiterSet && utf8.RuneCountInString(o.outputDelimiter) != 1 {
+		return errors.New("the output delimiter must be a single character in --csv mode")
+	}
+	return nil
+}
+
+// Функция сообщает, что элемент списка полей - имя колонки, а не номер или диапазон
+func isFieldName(item string) bool {
+	return strings.Trim(item, "0123456789-") != ""
+}
+
+// Функция проверяет номера и диапазоны в списке полей --csv и сообщает, есть ли в нем имена колонок
+func checkFieldNames(fields string) (bool, error) {
+	if fields == "" {
+		_, err := parseRanges(fields, unitField)
+		return false, err
+	}
+
+	names := false
+	for _, item := range splitList(fields) {
+		if isFieldName(item) {
+			names = true
+			continue
+		}
+		if _, err := parseRange(item, unitField); err != nil {
+			return false, err
+		}
+	}
+	return names, nil
+}
+
+// Функция разбирает список полей, в котором вместо номеров могут быть имена колонок из заголовка.
+// Порядок и повторы сохраняются
+func resolveFieldNames(fields string, header []string) (rangeList, error) {
+	var list rangeList
+	for _, item := range splitList(fields) {
+		if !isFieldName(item) {
+			r, err := parseRange(item, unitField)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, r)
+			continue
+		}
+
+		found := false
+		for i, name := range header {
+			if name == item {
+				list = append(list, posRange{start: i + 1, end: i + 1})
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown field %q", item)
+		}
+	}
+	return list, nil
+}
+
+// Функция вырезает поля из CSV (RFC 4180): поля в кавычках могут содержать разделители
+// и переводы строк, вывод снова экранируется по правилам CSV.
+// Если в -f есть имена колонок, они ищутся в первой записи - заголовке
+func cutCSV(r io.Reader, w io.Writer, o cutOptions) error {
+	comma, _ := utf8.DecodeRuneInString(o.delimiter)
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	if o.outputDelimiterSet {
+		writer.Comma, _ = utf8.DecodeRuneInString(o.outputDelimiter)
+	}
+
+	list := o.list
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if first && list == nil {
+			if list, err = resolveFieldNames(o.fields, record); err != nil {
+				return err
+			}
+			list = o.arrange(list)
+		}
+
+		// Флаг -s (пропускаем записи без разделителя)
+		if o.separated && len(record) < 2 {
+			continue
+		}
+		selected := o.selectColumns(record, list)
+		if len(selected) == 0 {
+			continue
+		}
+		if err := writer.Write(selected); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
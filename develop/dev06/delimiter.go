@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Функция проверяет разделители и компилирует регулярное выражение для --regex
+func (o *cutOptions) compileDelimiter() error {
+	if o.delimiter == "" {
+		return errors.New("the delimiter must not be empty")
+	}
+	if !o.regex {
+		return nil
+	}
+
+	re, err := regexp.Compile(o.delimiter)
+	if err != nil {
+		return fmt.Errorf("invalid delimiter regexp: %w", err)
+	}
+	if re.MatchString("") {
+		return fmt.Errorf("delimiter regexp %q matches an empty string", o.delimiter)
+	}
+	o.re = re
+	return nil
+}
+
+// Функция разбивает строку на поля по разделителю -d (строке или регулярному выражению).
+// С --regex, как в awk, разделители в начале и в конце строки не дают пустых полей
+func (o cutOptions) split(line string) []string {
+	if o.re != nil {
+		fields := o.re.Split(line, -1)
+		if len(fields) > 1 && fields[0] == "" {
+			fields = fields[1:]
+		}
+		if n := len(fields); n > 1 && fields[n-1] == "" {
+			fields = fields[:n-1]
+		}
+		return fields
+	}
+	return strings.Split(line, o.delimiter)
+}
+
+// Функция сообщает, есть ли в строке разделитель
+func (o cutOptions) hasDelimiter(line string) bool {
+	if o.re != nil {
+		return o.re.MatchString(line)
+	}
+	return strings.Contains(line, o.delimiter)
+}
+
+// Функция возвращает разделитель полей в выводе: --output-delimiter, а если он не задан -
+// входной разделитель или, для --regex, пробел, как в awk
+func (o cutOptions) fieldSeparator() string {
+	switch {
+	case o.outputDelimiterSet:
+		return o.outputDelimiter
+	case o.re != nil:
+		return " "
+	}
+	return o.delimiter
+}
+
+// Функция возвращает разделитель между несмежными диапазонами -b и -c:
+// по умолчанию диапазоны склеиваются
+func (o cutOptions) rangeSeparator() string {
+	if o.outputDelimiterSet {
+		return o.outputDelimiter
+	}
+	return ""
+}
+
+// Функция возвращает символ конца записи: перевод строки или, с -z, нулевой байт
+func (o cutOptions) recordEnd() byte {
+	if o.zeroTerminated {
+		return 0
+	}
+	return '\n'
+}
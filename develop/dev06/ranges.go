@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Единицы списка: поля (-f), байты (-b) или символы (-c)
+const (
+	unitField = "field"
+	unitByte  = "byte"
+	unitChar  = "character"
+)
+
+// posRange - диапазон номеров, нумерация с 1. end == 0 - до конца строки
+type posRange struct {
+	start int
+	end   int
+}
+
+// rangeList - список из -f, -b или -c: диапазоны по возрастанию, без пересечений,
+// а с --preserve-order - в том порядке, в котором они заданы
+type rangeList []posRange
+
+// Функция разбирает список вида "1,3-5,7-,-2", сохраняя порядок и повторы диапазонов.
+// "N-" - от N до конца строки, "-M" - от первого до M. unit - единица списка для сообщений об ошибках
+func parseRanges(s, unit string) (rangeList, error) {
+	if s == "" {
+		return nil, fmt.Errorf("%ss are numbered from 1", unit)
+	}
+
+	var list rangeList
+	for _, item := range splitList(s) {
+		r, err := parseRange(item, unit)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, nil
+}
+
+// Функция разбивает список на элементы через запятую
+func splitList(s string) []string {
+	return strings.Split(s, ",")
+}
+
+// Функция разбирает один элемент списка
+func parseRange(item, unit string) (posRange, error) {
+	startStr, endStr, isRange := strings.Cut(item, "-")
+	if !isRange {
+		n, err := parseNumber(item, unit)
+		return posRange{start: n, end: n}, err
+	}
+	if startStr == "" && endStr == "" {
+		return posRange{}, fmt.Errorf("invalid range with no endpoint: %q", item)
+	}
+
+	r := posRange{start: 1}
+	var err error
+	if startStr != "" {
+		if r.start, err = parseNumber(startStr, unit); err != nil {
+			return r, err
+		}
+	}
+	if endStr != "" {
+		if r.end, err = parseNumber(endStr, unit); err != nil {
+			return r, err
+		}
+		if r.end < r.start {
+			return r, fmt.Errorf("invalid decreasing range %q", item)
+		}
+	}
+	return r, nil
+}
+
+// Функция разбирает номер поля, байта или символа
+func parseNumber(s, unit string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || strings.HasPrefix(s, "+") {
+		return 0, fmt.Errorf("invalid %s value %q", unit, s)
+	}
+	if n < 1 {
+		return 0, fmt.Errorf("%ss are numbered from 1", unit)
+	}
+	return n, nil
+}
+
+// Функция сортирует диапазоны и объединяет пересекающиеся и соседние
+func (l rangeList) normalize() rangeList {
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].start < l[j].start
+	})
+
+	var merged rangeList
+	for _, r := range l {
+		last := len(merged) - 1
+		if last >= 0 && (merged[last].end == 0 || r.start <= merged[last].end+1) {
+			if merged[last].end != 0 && (r.end == 0 || r.end > merged[last].end) {
+				merged[last].end = r.end
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Функция сообщает, входит ли номер n в список
+func (l rangeList) contains(n int) bool {
+	for _, r := range l {
+		if n >= r.start && (r.end == 0 || n <= r.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// Функция выбирает байты строки (-b) и возвращает непрерывные куски выбранных байт.
+// С noSplit (-n) многобайтовый символ не разрезается: он выводится, если выбран его последний байт
+func selectBytes(line string, list rangeList, complement, noSplit bool) []string {
+	pieces := pieceBuilder{line: line}
+	for i := 0; i < len(line); {
+		size := 1
+		if noSplit {
+			_, size = utf8.DecodeRuneInString(line[i:])
+		}
+		pieces.add(i, i+size, list.contains(i+size) != complement)
+		i += size
+	}
+	return pieces.result()
+}
+
+// Функция выбирает символы строки (-c) и возвращает непрерывные куски выбранных символов.
+// Символ - руна UTF-8, некорректный байт считается отдельным символом
+func selectChars(line string, list rangeList, complement bool) []string {
+	pieces := pieceBuilder{line: line}
+	for i, n := 0, 1; i < len(line); n++ {
+		_, size := utf8.DecodeRuneInString(line[i:])
+		pieces.add(i, i+size, list.contains(n) != complement)
+		i += size
+	}
+	return pieces.result()
+}
+
+// pieceBuilder собирает выбранные части строки в непрерывные куски
+type pieceBuilder struct {
+	line       string
+	pieces     []string
+	start, end int  // границы текущего куска в байтах
+	open       bool // текущий кусок не закрыт
+}
+
+// add добавляет часть line[start:end], выбранную или нет
+func (b *pieceBuilder) add(start, end int, selected bool) {
+	switch {
+	case selected && b.open:
+		b.end = end
+	case selected:
+		b.start, b.end, b.open = start, end, true
+	case b.open:
+		b.pieces = append(b.pieces, b.line[b.start:b.end])
+		b.open = false
+	}
+}
+
+// result возвращает собранные куски
+func (b *pieceBuilder) result() []string {
+	if b.open {
+		b.pieces = append(b.pieces, b.line[b.start:b.end])
+		b.open = false
+	}
+	return b.pieces
+}
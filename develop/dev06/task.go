@@ -2,10 +2,12 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
+	"regexp"
 	"strings"
 )
 
@@ -23,9 +25,24 @@ import (
 */
 
 type cutOptions struct {
-	fields    string // -f
-	delimiter string // -d
-	separated bool   // -s
+	fields     string    // -f
+	delimiter  string    // -d
+	separated  bool      // -s
+	complement bool      // --complement
+	bytes      string    // -b
+	chars      string    // -c
+	noSplit    bool      // -n
+	mode       string    // что выбирается: unitField, unitByte или unitChar
+	list       rangeList // разобранный список -f, -b или -c
+
+	outputDelimiter    string         // --output-delimiter
+	outputDelimiterSet bool           // --output-delimiter задан явно
+	regex              bool           // --regex: -d - регулярное выражение
+	re                 *regexp.Regexp // скомпилированный разделитель для --regex
+	zeroTerminated     bool           // -z
+	csv                bool           // --csv
+	preserveOrder      bool           // --preserve-order
+	fill               bool           // --fill
 }
 
 var options cutOptions
@@ -34,82 +51,250 @@ func init() {
 	flag.StringVar(&options.fields, "f", "", "Select fields (e.g., 1,2-3)")
 	flag.StringVar(&options.delimiter, "d", "\t", "Column delimiter (default is TAB)")
 	flag.BoolVar(&options.separated, "s", false, "Only lines with the delimiter")
+	flag.BoolVar(&options.complement, "complement", false, "Output everything except the selected fields, bytes or characters")
+	flag.StringVar(&options.bytes, "b", "", "Select bytes (e.g., 1,2-3)")
+	flag.StringVar(&options.chars, "c", "", "Select characters (e.g., 1,2-3)")
+	flag.BoolVar(&options.noSplit, "n", false, "With -b, do not split multibyte characters")
+	flag.Func("output-delimiter", "Use `STRING` as the output delimiter (default is the input delimiter)", func(value string) error {
+		options.outputDelimiter = value
+		options.outputDelimiterSet = true
+		return nil
+	})
+	flag.BoolVar(&options.regex, "regex", false, "Interpret the -d delimiter as a regular expression (e.g., -d '[ \t]+'); leading and trailing delimiters are ignored, as in awk")
+	flag.BoolVar(&options.zeroTerminated, "z", false, "Line delimiter is NUL, not newline")
+	flag.BoolVar(&options.csv, "csv", false, "Parse input as CSV (RFC 4180); -f may list column names from the header")
+	flag.BoolVar(&options.preserveOrder, "preserve-order", false, "Output fields in the order given by -f, repeating duplicates (e.g., -f 3,1,1)")
+	flag.BoolVar(&options.fill, "fill", false, "Output an empty string for each selected field missing from the line")
 }
 
 func main() {
 	flag.Parse()
 
-	// Флаг -f
-	if options.fields == "" {
-		fmt.Fprintln(os.Stderr, "Error: -f flag is required")
+	// Флаги -f, -b, -c
+	if err := options.parseList(setFlags()); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	if err := options.compileDelimiter(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
 
-	// Читаем строки из STDIN
-	lines := readInput()
+	// Файлы из аргументов, по умолчанию (и для "-") - STDIN
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	os.Exit(run(options, files, os.Stdin, os.Stdout, os.Stderr))
+}
+
+// Функция возвращает имена флагов, заданных в командной строке
+func setFlags() map[string]bool {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// Функция выбирает режим по флагам -f, -b, -c и разбирает список
+func (o *cutOptions) parseList(set map[string]bool) error {
+	var list string
+	modes := 0
+	for _, m := range []struct {
+		flag, unit, list string
+	}{{"f", unitField, o.fields}, {"b", unitByte, o.bytes}, {"c", unitChar, o.chars}} {
+		if set[m.flag] {
+			o.mode, list = m.unit, m.list
+			modes++
+		}
+	}
+	switch {
+	case modes == 0:
+		return errors.New("you must specify a list of bytes, characters, or fields")
+	case modes > 1:
+		return errors.New("only one type of list may be specified")
+	case o.mode != unitField && (set["d"] || set["s"] || set["regex"]):
+		return errors.New("-d, -s and --regex may be specified only when operating on fields")
+	case o.mode != unitField && (o.preserveOrder || o.fill):
+		return errors.New("--preserve-order and --fill may be specified only when operating on fields")
+	case o.complement && (o.preserveOrder || o.fill):
+		return errors.New("--complement cannot be combined with --preserve-order or --fill")
+	}
+
+	if o.csv {
+		if err := o.checkCSV(set); err != nil {
+			return err
+		}
+		// имена колонок разбираются, когда будет прочитан заголовок, номера проверяются сразу
+		names, err := checkFieldNames(list)
+		if err != nil {
+			return err
+		}
+		if names {
+			return nil
+		}
+	}
+
+	ranges, err := parseRanges(list, o.mode)
+	if err != nil {
+		return err
+	}
+	o.list = o.arrange(ranges)
+	return nil
+}
 
-	// Обрабатываем каждую строку
-	for _, line := range lines {
-		processLine(line)
+// Функция приводит список к порядку вывода: по возрастанию и без повторов
+// или, с --preserve-order, как он задан
+func (o cutOptions) arrange(list rangeList) rangeList {
+	if o.preserveOrder {
+		return list
 	}
+	return list.normalize()
 }
 
-// Функция чтения строк из STDIN
-func readInput() []string {
-	var lines []string
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+// Функция обрабатывает файлы по очереди и возвращает код выхода: 0 - успех, 1 - была ошибка.
+// Ошибка в одном файле печатается в stderr и не мешает обработать остальные
+func run(o cutOptions, files []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	out := bufio.NewWriter(stdout)
+	status := 0
+	for _, name := range files {
+		err := cutFile(name, stdin, out, o)
+		if err == nil {
+			continue
+		}
+		// ошибка записи - дальше выводить некуда
+		if flushErr := out.Flush(); flushErr != nil {
+			fmt.Fprintln(stderr, "Error:", flushErr)
+			return 1
+		}
+		fmt.Fprintln(stderr, "Error:", err)
+		status = 1
 	}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
-		os.Exit(1)
+	if err := out.Flush(); err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return 1
 	}
+	return status
+}
 
-	return lines
+// Функция обрабатывает один файл ("-" - стандартный ввод)
+func cutFile(name string, stdin io.Reader, w io.Writer, o cutOptions) error {
+	r := stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("could not open file %s: %w", name, err)
+		}
+		defer file.Close()
+		r = file
+	}
+	return cut(r, w, o)
+}
+
+// Функция вырезает выбранные части из каждой записи r и пишет их в w.
+// Записи читаются и выводятся по одной, поэтому вход не копится в памяти, а длина строки не ограничена
+func cut(r io.Reader, w io.Writer, o cutOptions) error {
+	if o.csv {
+		return cutCSV(r, w, o)
+	}
+
+	br := bufio.NewReader(r)
+	for {
+		record, err := readRecord(br, o.recordEnd())
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := o.processLine(w, record); err != nil {
+			return err
+		}
+	}
+}
+
+// Функция читает запись любой длины без завершающего символа end.
+// Последняя запись без завершающего символа тоже возвращается
+func readRecord(br *bufio.Reader, end byte) (string, error) {
+	raw, err := br.ReadString(end)
+	if err == io.EOF && raw != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(raw, string(end)), nil
 }
 
 // Функция обработки строки
-func processLine(line string) {
+func (o cutOptions) processLine(w io.Writer, line string) error {
+	switch o.mode {
+	case unitByte:
+		return o.writeRecord(w, strings.Join(selectBytes(line, o.list, o.complement, o.noSplit), o.rangeSeparator()))
+	case unitChar:
+		return o.writeRecord(w, strings.Join(selectChars(line, o.list, o.complement), o.rangeSeparator()))
+	}
+
 	// Флаг -s (пропускаем строки без разделителя)
-	if options.separated && !strings.Contains(line, options.delimiter) {
-		return
+	if o.separated && !o.hasDelimiter(line) {
+		return nil
 	}
 
-	columns := strings.Split(line, options.delimiter)	// разбиваем строку на колонки
-	selected := selectFields(columns, options.fields)	// получаем выбранные колонки
+	columns := o.split(line)	// разбиваем строку на колонки
+	selected := o.selectColumns(columns, o.list)	// получаем выбранные колонки
 
 	// Выводим результат
-	if len(selected) > 0 {
-		fmt.Println(strings.Join(selected, options.delimiter))
+	if len(selected) == 0 {
+		return nil
 	}
+	return o.writeRecord(w, strings.Join(selected, o.fieldSeparator()))
 }
 
-// Функция выбора указанных колонок
-func selectFields(columns []string, fields string) []string {
+// Функция выводит запись с завершающим переводом строки или, с -z, нулевым байтом
+func (o cutOptions) writeRecord(w io.Writer, record string) error {
+	_, err := io.WriteString(w, record+string(o.recordEnd()))
+	return err
+}
+
+// Функция выбирает колонки по списку с учетом --complement, --preserve-order и --fill
+func (o cutOptions) selectColumns(columns []string, list rangeList) []string {
+	if o.preserveOrder || o.fill {
+		return selectOrdered(columns, list, o.fill)
+	}
+	return selectFields(columns, list, o.complement)
+}
+
+// Функция выбирает колонки в порядке диапазонов списка, повторы выводятся повторно.
+// С fill вместо отсутствующих колонок выводятся пустые строки; диапазон "N-" ими не дополняется
+func selectOrdered(columns []string, list rangeList, fill bool) []string {
 	var result []string
-	ranges := strings.Split(fields, ",")
-
-	for _, r := range ranges {
-		if strings.Contains(r, "-") {
-			// Обрабатываем диапазон
-			parts := strings.Split(r, "-")
-			start, _ := strconv.Atoi(parts[0])
-			end, _ := strconv.Atoi(parts[1])
-			for i := start; i <= end && i <= len(columns); i++ {
-				if i > 0 {
-					result = append(result, columns[i-1])
-				}
-			}
-		} else {
-			// Обрабатываем одиночный номер
-			index, _ := strconv.Atoi(r)
-			if index > 0 && index <= len(columns) {
-				result = append(result, columns[index-1])
+	for _, r := range list {
+		end := r.end
+		if end == 0 {
+			end = len(columns)
+		}
+		for i := r.start; i <= end; i++ {
+			switch {
+			case i <= len(columns):
+				result = append(result, columns[i-1])
+			case fill:
+				result = append(result, "")
 			}
 		}
 	}
+	return result
+}
 
+// Функция выбора указанных колонок. Колонки выводятся по возрастанию номеров, каждая один раз,
+// с complement - все колонки, кроме указанных
+func selectFields(columns []string, list rangeList, complement bool) []string {
+	var result []string
+	for i, column := range columns {
+		if list.contains(i+1) != complement {
+			result = append(result, column)
+		}
+	}
 	return result
 }
@@ -2,14 +2,18 @@ package main
 
 import (
 	"bytes"
+	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
 
 func runCommand(input string, args []string) (string, error) {
 	// Create a command to execute the Go program with provided arguments
-	cmd := exec.Command("go", append([]string{"run", "task.go"}, args...)...)
+	cmd := exec.Command("go", append([]string{"run", "."}, args...)...)
 
 	// Set the input for the command
 	cmd.Stdin = strings.NewReader(input)
@@ -48,6 +52,108 @@ func TestCutUtility(t *testing.T) {
 			args:     []string{"-f", "1", "-s"},
 			expected: "apple\n",
 		},
+		{
+			name:     "Open-ended ranges",
+			input:    "a\tb\tc\td\te\n",
+			args:     []string{"-f", "-2,4-"},
+			expected: "a\tb\td\te\n",
+		},
+		{
+			name:     "Ascending order without duplicates",
+			input:    "a\tb\tc\n",
+			args:     []string{"-f", "3,1,1-2"},
+			expected: "a\tb\tc\n",
+		},
+		{
+			name:     "Byte ranges",
+			input:    "abcdef\n",
+			args:     []string{"-b", "1-2,5-"},
+			expected: "abef\n",
+		},
+		{
+			name:     "Character ranges count runes",
+			input:    "привет\nмир\n",
+			args:     []string{"-c", "2-3"},
+			expected: "ри\nир\n",
+		},
+		{
+			name:     "Bytes without splitting characters",
+			input:    "привет\n",
+			args:     []string{"-b", "1-5", "-n"},
+			expected: "пр\n",
+		},
+		{
+			name:     "Output delimiter",
+			input:    "a\tb\tc\n",
+			args:     []string{"-f", "1,3", "--output-delimiter", ", "},
+			expected: "a, c\n",
+		},
+		{
+			name:     "Multi-character delimiter",
+			input:    "a::b::c\n",
+			args:     []string{"-f", "2-", "-d", "::"},
+			expected: "b::c\n",
+		},
+		{
+			name:     "Regex delimiter",
+			input:    "a  b\t c\nno-delimiter\n",
+			args:     []string{"-f", "1,3", "-d", "[ \t]+", "--regex", "-s"},
+			expected: "a c\n",
+		},
+		{
+			name:     "Regex delimiter ignores leading and trailing delimiters",
+			input:    "  a   b c \n\t\n",
+			args:     []string{"-f", "1,2,3", "-d", "[ \t]+", "--regex"},
+			expected: "a b c\n\n",
+		},
+		{
+			name:     "Output delimiter between byte ranges",
+			input:    "abcdef\n",
+			args:     []string{"-b", "1-2,4,5", "--output-delimiter", "|"},
+			expected: "ab|de\n",
+		},
+		{
+			name:     "NUL-terminated records",
+			input:    "a;b\nc\x00d;e",
+			args:     []string{"-f", "2", "-d", ";", "-z"},
+			expected: "b\nc\x00e\x00",
+		},
+		{
+			name:     "CSV with quoted delimiters",
+			input:    "id,name,city\n1,\"Doe, John\",Paris\n",
+			args:     []string{"--csv", "-f", "2-"},
+			expected: "name,city\n\"Doe, John\",Paris\n",
+		},
+		{
+			name:     "CSV fields by header name",
+			input:    "id,name,email\n1,Ann,ann@example.com\n",
+			args:     []string{"--csv", "-f", "email,1"},
+			expected: "id,email\n1,ann@example.com\n",
+		},
+		{
+			name:     "Complement",
+			input:    "a;b;c;d\n",
+			args:     []string{"-f", "2-3", "-d", ";", "--complement"},
+			expected: "a;d\n",
+		},
+		{
+			name:     "Preserve order with duplicates",
+			input:    "a;b;c\nd;e;f\n",
+			args:     []string{"-f", "3,1,1", "-d", ";", "--preserve-order"},
+			expected: "c;a;a\nf;d;d\n",
+		},
+		{
+			name:     "Fill missing fields",
+			input:    "a;b;c\nd\n",
+			args:     []string{"-f", "3,1,4", "-d", ";", "--preserve-order", "--fill"},
+			expected: "c;a;\n;d;\n",
+		},
+		{
+			name:     "CSV header names in given order",
+			input:    "id,name,email\n1,Ann,ann@example.com\n",
+			args:     []string{"--csv", "-f", "email,id", "--preserve-order"},
+			expected: "email,id\nann@example.com,1\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -63,3 +169,290 @@ func TestCutUtility(t *testing.T) {
 		})
 	}
 }
+
+func TestCutInvalidFields(t *testing.T) {
+	for _, fields := range []string{"a", "3-1", "0", "-", "1,,2", ""} {
+		t.Run(fields, func(t *testing.T) {
+			if _, err := runCommand("a\tb\n", []string{"-f", fields}); err == nil {
+				t.Errorf("Expected an error for -f %q", fields)
+			}
+		})
+	}
+}
+
+func TestCutInvalidModes(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-f", "1", "-b", "1"},
+		{"-c", "1", "-d", ";"},
+		{"-b", "0"},
+		{"-c", "2-1"},
+		{"-f", "1", "-d", ""},
+		{"-f", "1", "-d", "x*", "--regex"},
+		{"-f", "1", "-d", "(", "--regex"},
+		{"-b", "1", "--regex"},
+		{"-b", "1", "--csv"},
+		{"-f", "1", "--csv", "-d", "::"},
+		{"-f", "missing", "--csv"},
+		{"-f", "3-1", "--csv"},
+		{"-f", "email,0", "--csv"},
+		{"-f", "1,,2", "--csv"},
+		{"-b", "1", "--preserve-order"},
+		{"-f", "1", "--fill", "--complement"},
+	} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			if _, err := runCommand("abc\n", args); err == nil {
+				t.Errorf("Expected an error for %q", args)
+			}
+		})
+	}
+}
+
+func TestSelectBytesAndChars(t *testing.T) {
+	list := func(s string) rangeList {
+		l, err := parseRanges(s, unitByte)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l.normalize()
+	}
+	// куски выводятся через --output-delimiter, здесь - через "|"
+	join := func(pieces []string) string {
+		return strings.Join(pieces, "|")
+	}
+
+	tests := []struct {
+		name     string
+		got      string
+		expected string
+	}{
+		{"Bytes split characters", join(selectBytes("ёж", list("1-3"), false, false)), "ё\xd0"},
+		{"Character is kept if its last byte is selected", join(selectBytes("ёж", list("2-3"), false, true)), "ё"},
+		{"Character is dropped if its last byte is not selected", join(selectBytes("ёж", list("3"), false, true)), ""},
+		{"Bytes complement", join(selectBytes("abcd", list("2-3"), true, false)), "a|d"},
+		{"Characters", join(selectChars("ёжик", list("2,4-"), false)), "ж|к"},
+		{"Characters complement", join(selectChars("ёжик", list("-2"), true)), "ик"},
+		{"Invalid UTF-8 byte is one character", join(selectChars("a\xffb", list("2-"), false)), "\xffb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, tt.got)
+			}
+		})
+	}
+}
+
+func TestParseFieldList(t *testing.T) {
+	tests := []struct {
+		fields   string
+		expected rangeList
+		wantErr  bool
+	}{
+		{fields: "1,3", expected: rangeList{{1, 1}, {3, 3}}},
+		{fields: "3-", expected: rangeList{{3, 0}}},
+		{fields: "-2", expected: rangeList{{1, 2}}},
+		{fields: "5,1-3,2,4", expected: rangeList{{1, 5}}},
+		{fields: "2-3,6-,8", expected: rangeList{{2, 3}, {6, 0}}},
+		{fields: "a", wantErr: true},
+		{fields: "3-1", wantErr: true},
+		{fields: "0-2", wantErr: true},
+		{fields: "1-x", wantErr: true},
+		{fields: "+1", wantErr: true},
+		{fields: "-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fields, func(t *testing.T) {
+			list, err := parseRanges(tt.fields, unitField)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err == nil {
+				list = list.normalize()
+			}
+			if !tt.wantErr && !reflect.DeepEqual(list, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, list)
+			}
+		})
+	}
+}
+
+func TestParseListCSV(t *testing.T) {
+	tests := []struct {
+		fields   string
+		wantErr  bool
+		expected rangeList
+	}{
+		{fields: "2,1", expected: rangeList{{1, 2}}},
+		{fields: "email,1", expected: nil},
+		{fields: "3-1", wantErr: true},
+		{fields: "0", wantErr: true},
+		{fields: "1,,2", wantErr: true},
+		{fields: "email,0", wantErr: true},
+		{fields: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fields, func(t *testing.T) {
+			// ошибки в номерах видны до чтения заголовка, даже если вход пуст
+			o := cutOptions{fields: tt.fields, csv: true}
+			err := o.parseList(map[string]bool{"f": true, "csv": true})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(o.list, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, o.list)
+			}
+		})
+	}
+}
+
+func TestCutCSV(t *testing.T) {
+	input := "name,note\n\"Doe, John\",\"says \"\"hi\"\"\nthere\"\nAnn,ok\n"
+
+	tests := []struct {
+		name     string
+		options  cutOptions
+		expected string
+	}{
+		{
+			name:     "Quoted field with newline and quotes is re-quoted",
+			options:  cutOptions{fields: "note", delimiter: ","},
+			expected: "note\n\"says \"\"hi\"\"\nthere\"\nok\n",
+		},
+		{
+			name:     "Output delimiter",
+			options:  cutOptions{fields: "1-2", delimiter: ",", outputDelimiter: ";", outputDelimiterSet: true},
+			expected: "name;note\nDoe, John;\"says \"\"hi\"\"\nthere\"\nAnn;ok\n",
+		},
+		{
+			name:     "Complement by name",
+			options:  cutOptions{fields: "note", delimiter: ",", complement: true},
+			expected: "name\n\"Doe, John\"\nAnn\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := cutCSV(strings.NewReader(input), &out, tt.options); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("Expected output:\n%q\nGot:\n%q", tt.expected, out.String())
+			}
+		})
+	}
+
+	// незакрытая кавычка - ошибка разбора CSV
+	err := cutCSV(strings.NewReader("a,\"b\n"), io.Discard, cutOptions{fields: "1", delimiter: ",", list: rangeList{{1, 1}}})
+	if err == nil {
+		t.Error("Expected an error for malformed CSV")
+	}
+}
+
+func TestCut(t *testing.T) {
+	long := strings.Repeat("x", 1<<20)
+
+	tests := []struct {
+		name     string
+		options  cutOptions
+		input    string
+		expected string
+	}{
+		{
+			name:     "Fields",
+			options:  cutOptions{mode: unitField, delimiter: "\t", list: rangeList{{2, 2}}},
+			input:    "a\tb\tc\nd\te\tf\n",
+			expected: "b\ne\n",
+		},
+		{
+			name:     "Last line without newline",
+			options:  cutOptions{mode: unitField, delimiter: ";", list: rangeList{{1, 1}}},
+			input:    "a;b\nc;d",
+			expected: "a\nc\n",
+		},
+		{
+			name:     "Line longer than 64KB",
+			options:  cutOptions{mode: unitField, delimiter: "\t", list: rangeList{{2, 2}}},
+			input:    "a\t" + long + "\tc\n",
+			expected: long + "\n",
+		},
+		{
+			name:     "NUL-terminated records",
+			options:  cutOptions{mode: unitChar, list: rangeList{{1, 1}}, zeroTerminated: true},
+			input:    "ab\x00cd",
+			expected: "a\x00c\x00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := cut(strings.NewReader(tt.input), &out, tt.options); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("Expected output:\n%.80q\nGot:\n%.80q", tt.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestSelectOrdered(t *testing.T) {
+	columns := []string{"a", "b", "c"}
+
+	tests := []struct {
+		name     string
+		list     string
+		fill     bool
+		expected []string
+	}{
+		{name: "Order and duplicates", list: "3,1,1", expected: []string{"c", "a", "a"}},
+		{name: "Overlapping ranges", list: "2-3,1-2", expected: []string{"b", "c", "a", "b"}},
+		{name: "Missing fields are skipped", list: "4,2,5-6", expected: []string{"b"}},
+		{name: "Missing fields are filled", list: "4,2,5-6", fill: true, expected: []string{"", "b", "", ""}},
+		{name: "Open range is not filled", list: "2-", fill: true, expected: []string{"b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := parseRanges(tt.list, unitField)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := selectOrdered(columns, list, tt.fill); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("a\tb\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("c\td\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	o := cutOptions{mode: unitField, delimiter: "\t", list: rangeList{{2, 2}}}
+
+	var stdout, stderr bytes.Buffer
+	status := run(o, []string{first, "-", second}, strings.NewReader("e\tf\n"), &stdout, &stderr)
+	if status != 0 || stdout.String() != "b\nf\nd\n" {
+		t.Errorf("Expected status 0 and %q, got %d and %q (stderr %q)", "b\nf\nd\n", status, stdout.String(), stderr.String())
+	}
+
+	// отсутствующий файл - ошибка, но остальные файлы обрабатываются
+	stdout.Reset()
+	stderr.Reset()
+	status = run(o, []string{filepath.Join(dir, "missing.txt"), second}, strings.NewReader(""), &stdout, &stderr)
+	if status != 1 || stdout.String() != "d\n" || !strings.Contains(stderr.String(), "missing.txt") {
+		t.Errorf("Expected status 1, output %q and an error, got %d, %q and %q", "d\n", status, stdout.String(), stderr.String())
+	}
+}
@@ -0,0 +1,130 @@
+// Package conc - комбинаторы сигнальных каналов: объединение по "или" и по "и",
+// остановка чтения по сигналу и переходники к context.Context и обратно.
+//
+// Сигналом считается закрытие канала или любое значение из него, как у or() в dev07.
+// В отличие от or(), горутины пакета не остаются висеть после того, как сработал результат.
+// Чтобы прекратить ожидание раньше, Or достаточно передать свой канал отмены среди входов,
+// а And и OrDone принимают его параметром done. Без этого горутина ждет, пока сработают входы,
+// поэтому в долгоживущих сервисах входы, которые могут не закрыться, нужно сопровождать отменой
+package conc
+
+import "context"
+
+// Функция возвращает закрытый канал
+func closed[T any]() <-chan T {
+	c := make(chan T)
+	close(c)
+	return c
+}
+
+// Or возвращает канал, который закрывается, как только приходит сигнал из любого из channels.
+// Без каналов возвращается закрытый канал, с одним - сам этот канал
+func Or[T any](channels ...<-chan T) <-chan T {
+	switch len(channels) {
+	case 0:
+		return closed[T]()
+	case 1:
+		return channels[0]
+	}
+
+	orDone := make(chan T)
+	go func() {
+		defer close(orDone)
+
+		switch len(channels) {
+		case 2:
+			select {
+			case <-channels[0]:
+			case <-channels[1]:
+			}
+		default:
+			// остаток объединяем рекурсивно и передаем ему orDone: когда сработает один из первых
+			// трех каналов, горутины остатка тоже завершатся.
+			// Остаток копируется, чтобы append не испортил срез вызывающего
+			rest := make([]<-chan T, 0, len(channels)-2)
+			rest = append(rest, channels[3:]...)
+			rest = append(rest, orDone)
+			select {
+			case <-channels[0]:
+			case <-channels[1]:
+			case <-channels[2]:
+			case <-Or(rest...):
+			}
+		}
+	}()
+	return orDone
+}
+
+// And возвращает канал, который закрывается, когда закрыты все channels или пришел сигнал из done.
+// Значения из каналов читаются и отбрасываются. Без каналов возвращается закрытый канал
+func And[D, T any](done <-chan D, channels ...<-chan T) <-chan T {
+	if len(channels) == 0 {
+		return closed[T]()
+	}
+
+	andDone := make(chan T)
+	go func() {
+		defer close(andDone)
+		// каналы ждем по очереди: все равно нужно дождаться каждого
+		for _, c := range channels {
+			for open := true; open; {
+				select {
+				case <-done:
+					return
+				case _, open = <-c:
+				}
+			}
+		}
+	}()
+	return andDone
+}
+
+// OrDone передает значения из c, пока c не закрыт и не пришел сигнал из done.
+// Возвращенный канал нужно читать до закрытия или подать сигнал в done, иначе горутина не завершится
+func OrDone[D, T any](done <-chan D, c <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-done:
+				return
+			case v, ok := <-c:
+				if !ok {
+					return
+				}
+				select {
+				case out <- v:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// WithDone возвращает контекст, который отменяется вместе с parent или по сигналу из done.
+// cancel нужно вызвать, когда контекст больше не нужен, как и для context.WithCancel
+func WithDone[T any](parent context.Context, done <-chan T) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// Done возвращает канал, который закрывается при отмене ctx. В отличие от ctx.Done(),
+// тип элементов канала любой, например interface{} для or().
+// Горутина не создается: канал закрывается через context.AfterFunc
+func Done[T any](ctx context.Context) <-chan T {
+	c := make(chan T)
+	context.AfterFunc(ctx, func() {
+		close(c)
+	})
+	return c
+}
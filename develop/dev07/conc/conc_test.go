@@ -0,0 +1,236 @@
+package conc
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Функция проверяет в конце теста, что все горутины, созданные в нем, завершились
+func checkLeaks(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				t.Errorf("Goroutine leak: %d goroutines before, %d after", before, runtime.NumGoroutine())
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
+
+// Функция проверяет, что канал закрыт или закроется в течение секунды
+func expectClosed[T any](t *testing.T, c <-chan T) {
+	t.Helper()
+	select {
+	case <-c:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the channel to be closed")
+	}
+}
+
+// Функция проверяет, что из канала пока ничего не пришло
+func expectOpen[T any](t *testing.T, c <-chan T) {
+	t.Helper()
+	select {
+	case <-c:
+		t.Fatal("Expected the channel to be open")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// Функция создает n открытых каналов
+func makeChannels(n int) []chan struct{} {
+	channels := make([]chan struct{}, n)
+	for i := range channels {
+		channels[i] = make(chan struct{})
+	}
+	return channels
+}
+
+// Функция приводит каналы к каналам только для чтения
+func receiveOnly(channels []chan struct{}) []<-chan struct{} {
+	result := make([]<-chan struct{}, len(channels))
+	for i, c := range channels {
+		result[i] = c
+	}
+	return result
+}
+
+// Тест Or: срабатывает любой из каналов, и после этого не остается горутин
+func TestOr(t *testing.T) {
+	for _, n := range []int{2, 3, 4, 10, 100} {
+		for _, closeAt := range []int{0, n / 2, n - 1} {
+			t.Run(fmt.Sprintf("%d channels, close %d", n, closeAt), func(t *testing.T) {
+				checkLeaks(t)
+				channels := makeChannels(n)
+				done := Or(receiveOnly(channels)...)
+				expectOpen(t, done)
+
+				close(channels[closeAt])
+				expectClosed(t, done)
+			})
+		}
+	}
+}
+
+// Тест Or без каналов и с одним каналом
+func TestOrTrivial(t *testing.T) {
+	expectClosed(t, Or[int]())
+
+	c := make(chan int)
+	if Or(c) != (<-chan int)(c) {
+		t.Error("Expected the single channel to be returned as is")
+	}
+}
+
+// Тест Or со значением вместо закрытия и с каналами значений любого типа
+func TestOrValue(t *testing.T) {
+	checkLeaks(t)
+	a, b, c := make(chan string, 1), make(chan string), make(chan string)
+	a <- "stop"
+	expectClosed(t, Or[string](a, b, c))
+}
+
+// Тест Or не портит срез вызывающего
+func TestOrKeepsArguments(t *testing.T) {
+	checkLeaks(t)
+	channels := makeChannels(5)
+	inputs := make([]<-chan struct{}, len(channels), len(channels)+10)
+	copy(inputs, receiveOnly(channels))
+
+	done := Or(inputs...)
+	extended := inputs[:cap(inputs)]
+	for i := len(channels); i < len(extended); i++ {
+		if extended[i] != nil {
+			t.Fatalf("Or wrote past the end of the arguments at %d", i)
+		}
+	}
+
+	close(channels[4])
+	expectClosed(t, done)
+}
+
+// Тест And: закрывается только после закрытия всех каналов
+func TestAnd(t *testing.T) {
+	checkLeaks(t)
+	never := make(chan struct{})
+	expectClosed(t, And[struct{}, struct{}](never))
+
+	channels := makeChannels(3)
+	done := And(never, receiveOnly(channels)...)
+	close(channels[2])
+	close(channels[0])
+	expectOpen(t, done)
+
+	close(channels[1])
+	expectClosed(t, done)
+}
+
+// Тест And пропускает значения
+func TestAndValues(t *testing.T) {
+	checkLeaks(t)
+	values := make(chan int)
+	done := And(make(chan struct{}), values)
+	values <- 1
+	values <- 2
+	close(values)
+	expectClosed(t, done)
+}
+
+// Тест And: сигнал из done завершает горутину, даже если один из каналов никогда не закроется
+func TestAndDone(t *testing.T) {
+	checkLeaks(t)
+	cancel := make(chan struct{})
+	channels := makeChannels(2)
+	close(channels[0])
+	done := And(cancel, receiveOnly(channels)...)
+	expectOpen(t, done)
+
+	close(cancel)
+	expectClosed(t, done)
+}
+
+// Тест OrDone: значения передаются до закрытия входного канала
+func TestOrDone(t *testing.T) {
+	checkLeaks(t)
+	values := make(chan int, 3)
+	values <- 1
+	values <- 2
+	values <- 3
+	close(values)
+
+	var got []int
+	for v := range OrDone(make(chan struct{}), values) {
+		got = append(got, v)
+	}
+	if len(got) != 3 || got[0] != 1 || got[2] != 3 {
+		t.Errorf("Expected [1 2 3], got %v", got)
+	}
+}
+
+// Тест OrDone: сигнал из done завершает горутину, даже если вход не закрыт и вывод никто не читает
+func TestOrDoneStops(t *testing.T) {
+	checkLeaks(t)
+	done := make(chan struct{})
+	values := make(chan int, 1)
+	values <- 1
+	idle := OrDone(done, make(chan int))
+	blocked := OrDone(done, values)
+	expectOpen(t, idle)
+
+	close(done)
+	for range idle {
+	}
+	for range blocked {
+	}
+}
+
+// Тест WithDone: контекст отменяется по сигналу из канала, горутина завершается
+func TestWithDone(t *testing.T) {
+	checkLeaks(t)
+	done := make(chan int)
+	ctx, cancel := WithDone(context.Background(), done)
+	defer cancel()
+	expectOpen(t, ctx.Done())
+
+	close(done)
+	expectClosed(t, ctx.Done())
+}
+
+// Тест WithDone: отмена родителя или cancel завершает горутину без сигнала из канала
+func TestWithDoneCancel(t *testing.T) {
+	checkLeaks(t)
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := WithDone(parent, make(chan struct{}))
+	defer cancel()
+	cancelParent()
+	expectClosed(t, ctx.Done())
+
+	ctx, cancel = WithDone(context.Background(), make(chan struct{}))
+	cancel()
+	expectClosed(t, ctx.Done())
+}
+
+// Тест Done и совместная работа с Or
+func TestDone(t *testing.T) {
+	checkLeaks(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := Done[interface{}](ctx)
+	never := make(chan interface{})
+	combined := Or(done, never)
+	expectOpen(t, combined)
+
+	cancel()
+	expectClosed(t, done)
+	expectClosed(t, combined)
+
+	deadline, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	expectClosed(t, Done[struct{}](deadline))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+)
+
+// maxSelectCases - наибольшее число веток в одном reflect.Select
+const maxSelectCases = 65536
+
+// Функция объединяет каналы так же, как orRecursive, но ждет их все в одной горутине через reflect.Select.
+// Больше maxSelectCases каналов за раз ждать нельзя, поэтому на каждую такую пачку создается своя горутина.
+// Когда срабатывает любой канал, завершаются все горутины
+func orReflect(channels ...<-chan interface{}) <-chan interface{} {
+	// в каждой пачке одна ветка уходит на сам результат
+	return orBatched(channels, maxSelectCases-1)
+}
+
+// Функция ждет каналы пачками по batch, по одной горутине на пачку
+func orBatched(channels []<-chan interface{}, batch int) <-chan interface{} {
+	// если каналы не переданы, возвращаем закрытый канал
+	if len(channels) == 0 {
+		closedChan := make(chan interface{})
+		close(closedChan)
+		return closedChan
+	}
+
+	// если передан один канал, возвращаем его
+	if len(channels) == 1 {
+		return channels[0]
+	}
+
+	orDone := make(chan interface{})
+	var once sync.Once
+	for start := 0; start < len(channels); start += batch {
+		part := channels[start:min(start+batch, len(channels))]
+
+		// ветка 0 - сам результат: по ней завершаются горутины остальных пачек
+		cases := make([]reflect.SelectCase, 0, len(part)+1)
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(orDone)})
+		for _, c := range part {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c)})
+		}
+
+		go func() {
+			if chosen, _, _ := reflect.Select(cases); chosen > 0 {
+				once.Do(func() {
+					close(orDone)
+				})
+			}
+		}()
+	}
+	return orDone
+}
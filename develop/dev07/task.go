@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 	"time"
 )
 
@@ -38,8 +40,24 @@ start := time.Now()
 fmt.Printf(“fone after %v”, time.Since(start))
 */
 
+// or - объединение каналов, реализация выбирается флагом -impl
+var or func(channels ...<-chan interface{}) <-chan interface{} = orRecursive
+
+// orImplementations - реализации or() по именам для -impl
+var orImplementations = map[string]func(channels ...<-chan interface{}) <-chan interface{}{
+	"recursive": orRecursive,
+	"reflect":   orReflect,
+}
+
+var impl string // -impl
+
+func init() {
+	flag.StringVar(&impl, "impl", "recursive", "or() implementation: recursive (goroutine per pair of channels) or reflect (single reflect.Select)")
+}
+
 // Функция объединяет несколько каналов в один. Возвращает канал, который закрывается, как только закрывается любой из входных каналов.
-func or(channels ...<-chan interface{}) <-chan interface{} {
+// Горутины создаются рекурсивно, примерно по одной на каждый канал, и остаются заблокированными до закрытия
+func orRecursive(channels ...<-chan interface{}) <-chan interface{} {
 	// если каналы не переданы, возвращаем закрытый канал
 	if len(channels) == 0 {
 		closedChan := make(chan interface{})
@@ -67,8 +85,8 @@ func or(channels ...<-chan interface{}) <-chan interface{} {
 		default:
 			mid := len(channels) / 2
 			select {
-			case <-or(channels[:mid]...):
-			case <-or(channels[mid:]...):
+			case <-orRecursive(channels[:mid]...):
+			case <-orRecursive(channels[mid:]...):
 			}
 		}
 	}()
@@ -76,6 +94,13 @@ func or(channels ...<-chan interface{}) <-chan interface{} {
 }
 
 func main() {
+	flag.Parse()
+	var ok bool
+	if or, ok = orImplementations[impl]; !ok {
+		fmt.Fprintln(os.Stderr, "Error: unknown -impl", impl)
+		os.Exit(1)
+	}
+
 	// Функция для сигнального канала, кот. закроется ч/з заданное время.
 	sig := func(after time.Duration) <-chan interface{} {
 		c := make(chan interface{})
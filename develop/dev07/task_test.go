@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"runtime"
 	"testing"
 	"time"
 )
@@ -74,3 +76,154 @@ func TestOrImmediateClose(t *testing.T) {
 		t.Errorf("Expected immediate closure, but got %v", elapsed)
 	}
 }
+
+// Функция создает n открытых каналов и их же только для чтения
+func makeChannels(n int) ([]chan interface{}, []<-chan interface{}) {
+	channels := make([]chan interface{}, n)
+	inputs := make([]<-chan interface{}, n)
+	for i := range channels {
+		channels[i] = make(chan interface{})
+		inputs[i] = channels[i]
+	}
+	return channels, inputs
+}
+
+// Функция ждет, пока число горутин перестанет меняться, и возвращает его
+func settledGoroutines() int {
+	n := runtime.NumGoroutine()
+	for stable := 0; stable < 20; {
+		time.Sleep(100 * time.Microsecond)
+		if m := runtime.NumGoroutine(); m != n {
+			n, stable = m, 0
+		} else {
+			stable++
+		}
+	}
+	return n
+}
+
+// Тест обеих реализаций: срабатывает любой канал, закрытием или значением
+func TestOrImplementations(t *testing.T) {
+	for name, orImpl := range orImplementations {
+		for _, n := range []int{1, 2, 3, 10, 1000} {
+			positions := []int{0, n / 2, n - 1}
+			for i, pos := range positions {
+				if i > 0 && pos == positions[i-1] {
+					continue
+				}
+				t.Run(fmt.Sprintf("%s/%d channels/close %d", name, n, pos), func(t *testing.T) {
+					channels, inputs := makeChannels(n)
+					done := orImpl(inputs...)
+					select {
+					case <-done:
+						t.Fatal("Expected the result to be open")
+					case <-time.After(5 * time.Millisecond):
+					}
+
+					close(channels[pos])
+					select {
+					case <-done:
+					case <-time.After(time.Second):
+						t.Fatal("Expected the result to be closed")
+					}
+
+					for i, c := range channels {
+						if i != pos {
+							close(c)
+						}
+					}
+				})
+			}
+		}
+
+		t.Run(name+"/value", func(t *testing.T) {
+			channels, inputs := makeChannels(3)
+			done := orImpl(inputs...)
+			channels[1] <- "stop"
+			<-done
+		})
+	}
+}
+
+// Тест orBatched с маленькими пачками: срабатывает канал из любой пачки, и все горутины завершаются
+func TestOrBatched(t *testing.T) {
+	for _, pos := range []int{0, 4, 9} {
+		t.Run(fmt.Sprintf("close %d", pos), func(t *testing.T) {
+			before := settledGoroutines()
+			channels, inputs := makeChannels(10)
+			done := orBatched(inputs, 3)
+			if got := settledGoroutines() - before; got != 4 {
+				t.Errorf("Expected 4 goroutines for 4 batches, got %d", got)
+			}
+
+			close(channels[pos])
+			<-done
+			if got := settledGoroutines(); got != before {
+				t.Errorf("Expected all goroutines to exit, %d before, %d after", before, got)
+			}
+		})
+	}
+}
+
+// Бенчмарк реализаций or(): время и память на создание и срабатывание (ns/op, B/op, allocs/op),
+// число горутин в ожидании (goroutines, у каждой свой стек от 2 КБ) и время от закрытия канала
+// до закрытия результата (latency-ns)
+func BenchmarkOr(b *testing.B) {
+	for _, name := range []string{"recursive", "reflect"} {
+		orImpl := orImplementations[name]
+		for _, n := range []int{10, 1000, 10000} {
+			b.Run(fmt.Sprintf("%s/n=%d", name, n), func(b *testing.B) {
+				goroutines, latency := measureOr(orImpl, n)
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					channels, inputs := makeChannels(n)
+					b.StartTimer()
+
+					done := orImpl(inputs...)
+					close(channels[n/2])
+					<-done
+
+					// остальные каналы закрываем, чтобы recursive не оставлял горутины
+					b.StopTimer()
+					for j, c := range channels {
+						if j != n/2 {
+							close(c)
+						}
+					}
+					b.StartTimer()
+				}
+				b.ReportMetric(float64(goroutines), "goroutines")
+				b.ReportMetric(float64(latency.Nanoseconds()), "latency-ns")
+			})
+		}
+	}
+}
+
+// Функция измеряет число горутин, которые ждут n каналов, и среднее время срабатывания
+func measureOr(orImpl func(channels ...<-chan interface{}) <-chan interface{}, n int) (int, time.Duration) {
+	const samples = 5
+	var goroutines int
+	var latency time.Duration
+	for i := 0; i < samples; i++ {
+		channels, inputs := makeChannels(n)
+		before := settledGoroutines()
+		done := orImpl(inputs...)
+		goroutines = settledGoroutines() - before
+
+		start := time.Now()
+		close(channels[n/2])
+		<-done
+		latency += time.Since(start)
+
+		for j, c := range channels {
+			if j != n/2 {
+				close(c)
+			}
+		}
+		settledGoroutines()
+	}
+	return goroutines, latency / samples
+}